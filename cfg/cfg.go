@@ -16,6 +16,12 @@
 // For the the sake of simplicity, values are hardcoded.
 package cfg
 
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
 func GetArtifactRepoBase() string {
 	return "examples/artifacts/zzz-raw-host"
 }
@@ -24,6 +30,167 @@ func GetProjectKey() string {
 	return "ZZZ"
 }
 
+// GetProjectKeys returns the keys of all Jira projects, whose issues can be
+// referenced by commits.
+func GetProjectKeys() []string {
+	return []string{GetProjectKey()}
+}
+
 func IsGitEnabled() bool {
 	return false
 }
+
+// GetReportEnv returns the names of the environment variables, which are
+// exposed to report templates. Any other variable is not accessible.
+func GetReportEnv() []string {
+	return []string{"VERSION", "BUILD_NUMBER", "BUILD_URL"}
+}
+
+// GetWorkDir returns the directory, where reports, snapshots and the history
+// are written by default.
+func GetWorkDir() string {
+	return filepath.Join(os.TempDir(), "heimdall-dev")
+}
+
+// GetHistoryDir returns the directory, where the reports of all evaluations
+// are recorded.
+func GetHistoryDir() string {
+	return filepath.Join(GetWorkDir(), "history")
+}
+
+// GetTrends returns the expressions, which are evaluated against the reports
+// of the last releases and shown as trend charts.
+func GetTrends() map[string]string {
+	return map[string]string{
+		"Line coverage (%)": `100 * facts["jacoco.line_covered"] / (facts["jacoco.line_covered"] + facts["jacoco.line_missed"])`,
+		"Tests":             `facts["junit.tests"]`,
+	}
+}
+
+// GetTrendReleases returns the number of releases shown in trend charts.
+func GetTrendReleases() int {
+	return 10
+}
+
+// GetGitCacheDir returns the directory containing bare mirrors of remote Git
+// repositories. If empty, repositories are cloned into memory.
+func GetGitCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "heimdall-dev", "git")
+}
+
+// GetGitKeyring returns the armored OpenPGP keyring of trusted maintainers,
+// whose signatures on commits and tags are considered valid.
+func GetGitKeyring() string {
+	return filepath.Join(GetArtifactRepoBase(), "keys", "maintainers.asc")
+}
+
+// GetGitAllowedSigners returns the SSH keys of trusted maintainers in the
+// allowed_signers format of ssh-keygen.
+func GetGitAllowedSigners() string {
+	return filepath.Join(GetArtifactRepoBase(), "keys", "allowed_signers")
+}
+
+// GetAllowedDomains returns the email domains of authors, who are allowed to
+// contribute without being listed in the CLA file.
+func GetAllowedDomains() []string {
+	return []string{"acme.org"}
+}
+
+// GetCLAFile returns the file containing the email addresses of contributors,
+// who signed the Contributor License Agreement, one per line.
+func GetCLAFile() string {
+	return filepath.Join(GetArtifactRepoBase(), "cla.txt")
+}
+
+// IsReleaseTagEnabled returns whether the evaluated commits are tagged after
+// the release passed the gate.
+func IsReleaseTagEnabled() bool {
+	return false
+}
+
+// IsReleaseTagPushEnabled returns whether release tags are pushed to the
+// remote repository.
+func IsReleaseTagPushEnabled() bool {
+	return false
+}
+
+// GetReleaseTagFormat returns the format of release tags, which is applied
+// to the name of the release.
+func GetReleaseTagFormat() string {
+	return "release/%s"
+}
+
+// GetReleaseTagger returns the name and email of the creator of release tags.
+func GetReleaseTagger() (string, string) {
+	return "heimdall-dev", "heimdall-dev@localhost"
+}
+
+// GetAliasFile returns the YAML file, which maps every identity to its
+// aliases in Git, GitHub and Jira.
+func GetAliasFile() string {
+	return filepath.Join(GetArtifactRepoBase(), "aliases.yml")
+}
+
+// GetGitHubRetries returns how often GitHub requests are retried after
+// exceeding the rate limit.
+func GetGitHubRetries() int {
+	return 3
+}
+
+// GetGitHubMaxRateLimitWait returns the maximum duration to wait for the
+// reset of the GitHub rate limit. Requests fail, if the reset is later.
+func GetGitHubMaxRateLimitWait() time.Duration {
+	return 15 * time.Minute
+}
+
+// GetReleaseBranches returns the patterns of release branches, which are
+// checked for branch protection if the release revision is not a branch.
+func GetReleaseBranches() []string {
+	return []string{"release/*", "hotfix/*", "main", "master"}
+}
+
+// IsGitHubCheckEnabled returns whether the outcome of the gate is published
+// as GitHub check run on the evaluated commits.
+func IsGitHubCheckEnabled() bool {
+	return false
+}
+
+// IsGitHubCommentEnabled returns whether the outcome of the gate is
+// commented on the open pull requests of the evaluated commits.
+func IsGitHubCommentEnabled() bool {
+	return false
+}
+
+// GetGitHubCheckName returns the name of GitHub check runs and the context
+// of commit statuses.
+func GetGitHubCheckName() string {
+	return "heimdall-dev"
+}
+
+// GetGitLabRetries returns how often GitLab requests are retried after
+// exceeding the rate limit.
+func GetGitLabRetries() int {
+	return 3
+}
+
+// GetGitLabMaxRateLimitWait returns the maximum duration to wait for the
+// reset of the GitLab rate limit.
+func GetGitLabMaxRateLimitWait() time.Duration {
+	return 15 * time.Minute
+}
+
+// GetBitbucketRetries returns how often Bitbucket requests are retried after
+// exceeding the rate limit.
+func GetBitbucketRetries() int {
+	return 3
+}
+
+// GetBitbucketMaxRateLimitWait returns the maximum duration to wait for
+// Bitbucket to accept requests again after exceeding the rate limit.
+func GetBitbucketMaxRateLimitWait() time.Duration {
+	return 15 * time.Minute
+}
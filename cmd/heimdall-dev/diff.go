@@ -0,0 +1,86 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/gschauer/heimdall-dev"
+	"github.com/gschauer/heimdall-dev/evidence"
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/release"
+	"github.com/rs/zerolog/log"
+)
+
+// diffFormats maps the supported output formats to their template.
+var diffFormats = map[string]string{
+	"text":     "plugin/report/diff.txt",
+	"markdown": "plugin/report/diff.md",
+	"html":     "plugin/report/diff.html",
+}
+
+func diff(args []string) {
+	fl := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fl.String("format", "text", "output `FORMAT`: text, markdown or html")
+	out := fl.String("o", "", "write the output to `FILE` instead of stdout")
+	fl.Usage = usage(fl, "diff [OPTIONS] OLD_REPORT NEW_REPORT")
+	internal.MustNoErr(fl.Parse(args))
+	if fl.NArg() != 2 {
+		fl.Usage()
+		os.Exit(2)
+	}
+
+	name, ok := diffFormats[*format]
+	if !ok {
+		log.Fatal().Str("format", *format).Msg("Unsupported format")
+	}
+
+	d := release.Diff(readReport(fl.Arg(0)), readReport(fl.Arg(1)))
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f := internal.Must(os.Create(*out))
+		defer func() { _ = f.Close() }()
+		w = f
+	}
+
+	text := string(internal.Must(fs.ReadFile(heimdall.StaticFS, name)))
+	if *format == "html" {
+		internal.MustNoErr(htmltemplate.Must(htmltemplate.New(name).Parse(text)).Execute(w, d))
+	} else {
+		internal.MustNoErr(template.Must(template.New(name).Parse(text)).Execute(w, d))
+	}
+}
+
+// readReport reads a JSON report or the JSON report of an evidence bundle.
+func readReport(name string) (r release.Report) {
+	log.Debug().Str("file", name).Msg("Loading report")
+	var bs []byte
+	if strings.HasSuffix(name, ".json") {
+		bs = internal.Must(os.ReadFile(name))
+	} else {
+		b := internal.Must(evidence.ReadFile(name))
+		data, ok := b.File("reports/report.json")
+		bs = internal.MustOkMsgf(data, ok, "%s: missing JSON report in evidence bundle", name)
+	}
+	internal.MustNoErr(json.Unmarshal(bs, &r))
+	return
+}
@@ -0,0 +1,81 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/gschauer/heimdall-dev/evidence"
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/plugin"
+	"github.com/gschauer/heimdall-dev/release"
+	"github.com/gschauer/heimdall-dev/secret"
+	"github.com/rs/zerolog/log"
+)
+
+// writeBundle writes a signed evidence bundle containing the reports, the
+// facts of all plugins and the given input files, i.e., release YAMLs and
+// policies.
+func writeBundle(name, key string, rep release.Report, env map[string]any, inputs []string) {
+	if key == "" {
+		log.Fatal().Msg("Evidence bundle requires a signing key")
+	}
+	s := internal.Must(evidence.LoadSigner(key))
+
+	b := evidence.New(rep.New.String())
+	for ext, render := range reportFormats {
+		b.Add("reports/report."+ext, internal.Must(render(rep)))
+	}
+	for k, v := range plugin.Facts(env) {
+		bs := internal.Must(json.MarshalIndent(v, "", "  "))
+		b.Add(path.Join("facts", k+".json"), []byte(secret.Redact(string(bs))))
+	}
+	for _, f := range inputs {
+		bs := internal.Must(os.ReadFile(f))
+		b.Add(path.Join("inputs", filepath.ToSlash(filepath.Clean(f))), []byte(secret.Redact(string(bs))))
+	}
+
+	internal.MustNoErr(b.WriteFile(name, s))
+	log.Info().Str("path", name).Int("files", len(b.Manifest.Files)).Msg("Wrote evidence bundle")
+}
+
+func verify(args []string) {
+	fl := flag.NewFlagSet("verify", flag.ExitOnError)
+	key := fl.String("key", "", "trust the public key or certificate in PEM `FILE`")
+	fl.Usage = usage(fl, "verify [OPTIONS] BUNDLE")
+	internal.MustNoErr(fl.Parse(args))
+	if fl.NArg() != 1 {
+		fl.Usage()
+		os.Exit(2)
+	}
+
+	var trusted []byte
+	if *key == "" {
+		log.Warn().Msg("No trusted key given, verifying integrity only")
+	} else {
+		trusted = internal.Must(os.ReadFile(*key))
+	}
+
+	b := internal.Must(evidence.ReadFile(fl.Arg(0)))
+	if err := b.Verify(trusted); err != nil {
+		log.Fatal().Err(err).Str("file", fl.Arg(0)).Msg("Invalid evidence bundle")
+	}
+	log.Info().Str("file", fl.Arg(0)).Str("release", b.Manifest.Release).
+		Time("created", b.Manifest.Created).Int("files", len(b.Manifest.Files)).Msg("Verified evidence bundle")
+}
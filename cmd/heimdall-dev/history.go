@@ -0,0 +1,58 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gschauer/heimdall-dev/cfg"
+	"github.com/gschauer/heimdall-dev/history"
+	"github.com/gschauer/heimdall-dev/internal"
+)
+
+func historyCmd(args []string) {
+	fl := flag.NewFlagSet("history", flag.ExitOnError)
+	dir := fl.String("store", cfg.GetHistoryDir(), "read the results from `DIR`")
+	product := fl.String("product", "", "only consider reports of `PRODUCT`")
+	fl.Usage = usage(fl, "history [OPTIONS] list\n       heimdall-dev history [OPTIONS] show ID\n       heimdall-dev history [OPTIONS] query EXPR")
+	internal.MustNoErr(fl.Parse(args))
+
+	s := history.New(*dir)
+	switch {
+	case fl.Arg(0) == "list" && fl.NArg() == 1:
+		printEntries(internal.Must(s.List(*product)))
+	case fl.Arg(0) == "show" && fl.NArg() == 2:
+		bs := internal.Must(renderJSON(internal.Must(s.Get(fl.Arg(1)))))
+		_, _ = os.Stdout.Write(append(bs, '\n'))
+	case fl.Arg(0) == "query" && fl.NArg() == 2:
+		printEntries(internal.Must(s.Query(*product, fl.Arg(1))))
+	default:
+		fl.Usage()
+		os.Exit(2)
+	}
+}
+
+func printEntries(es []history.Entry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tPRODUCT\tRELEASE\tDATE\tVERDICT")
+	for _, e := range es {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.ID, e.Product, e.Release, e.Date.Format(time.RFC3339), e.Verdict)
+	}
+	_ = w.Flush()
+}
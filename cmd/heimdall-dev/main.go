@@ -15,11 +15,10 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
 	"fmt"
-	"html/template"
-	"io"
-	"io/fs"
-	stdlog "log"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -29,16 +28,20 @@ import (
 
 	"github.com/antonmedv/expr"
 	"github.com/asaskevich/govalidator"
-	"github.com/gschauer/heimdall-dev"
 	"github.com/gschauer/heimdall-dev/cfg"
+	"github.com/gschauer/heimdall-dev/history"
 	"github.com/gschauer/heimdall-dev/internal"
 	"github.com/gschauer/heimdall-dev/plugin"
+	_ "github.com/gschauer/heimdall-dev/plugin/audit"
+	_ "github.com/gschauer/heimdall-dev/plugin/bitbucket"
 	_ "github.com/gschauer/heimdall-dev/plugin/git"
 	_ "github.com/gschauer/heimdall-dev/plugin/github"
+	_ "github.com/gschauer/heimdall-dev/plugin/gitlab"
 	_ "github.com/gschauer/heimdall-dev/plugin/java"
 	_ "github.com/gschauer/heimdall-dev/plugin/jira"
 	"github.com/gschauer/heimdall-dev/release"
 	"github.com/gschauer/heimdall-dev/res"
+	"github.com/gschauer/heimdall-dev/secret"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v3"
@@ -47,41 +50,155 @@ import (
 var version = 0
 
 func main() {
-	os.Args = []string{
-		os.Args[0],
-		"examples/releases/ZZZ_1.3.yml",
-		"examples/releases/ZZZ_1.4.yml",
-		"examples/checks.yml",
+	if len(os.Args) == 1 {
+		os.Args = append(os.Args,
+			"examples/releases/ZZZ_1.3.yml",
+			"examples/releases/ZZZ_1.4.yml",
+			"examples/checks_without_git.yml",
+		)
 	}
-	if len(os.Args) != 4 {
-		stdlog.Fatalf("Usage: %s OLD_RELEASE NEW_RELEASE CHECKS\n", filepath.Base(os.Args[0]))
+
+	switch os.Args[1] {
+	case "verify":
+		verify(os.Args[2:])
+	case "diff":
+		diff(os.Args[2:])
+	case "history":
+		historyCmd(os.Args[2:])
+	default:
+		run(os.Args[1:])
+	}
+}
+
+func usage(fl *flag.FlagSet, args string) func() {
+	return func() {
+		_, _ = fmt.Fprintf(fl.Output(), "Usage: %s %s\n", filepath.Base(os.Args[0]), args)
+		fl.PrintDefaults()
+	}
+}
+
+func run(args []string) {
+	fl := flag.NewFlagSet("run", flag.ExitOnError)
+	outDir := fl.String("out", cfg.GetWorkDir(), "write the reports to `DIR`")
+	bundle := fl.String("evidence-bundle", "", "write a signed evidence bundle to `FILE`")
+	key := fl.String("signing-key", os.Getenv("HEIMDALL_SIGNING_KEY"), "sign the evidence bundle with the private key in PEM `FILE`")
+	snapshot := fl.String("snapshot", "", "save the facts to `DIR` (default: work directory)")
+	historyDir := fl.String("store", cfg.GetHistoryDir(), "record the results in `DIR`")
+	factsFrom := fl.String("facts-from", "", "re-evaluate the facts from the snapshot in `DIR` without network access")
+	publish := fl.Bool("publish", false, "publish the outcome, e.g. create release tags")
+	dryRun := fl.Bool("dry-run", false, "only show what would be published")
+	fl.Usage = usage(fl, "[OPTIONS] OLD_RELEASE NEW_RELEASE CHECKS\n       heimdall-dev -facts-from DIR [OPTIONS] CHECKS")
+	internal.MustNoErr(fl.Parse(args))
+	if (*factsFrom == "" && fl.NArg() != 3) || (*factsFrom != "" && fl.NArg() != 1) {
+		fl.Usage()
+		os.Exit(2)
+	}
+	if *factsFrom != "" && *publish {
+		// replays must not act on a recorded verdict, e.g. by pushing tags
+		_, _ = fmt.Fprintln(fl.Output(), "-publish cannot be combined with -facts-from, use -dry-run instead")
+		os.Exit(2)
 	}
 
-	oldRel, newRel := loadYAML[release.Info](os.Args[1]), loadYAML[release.Info](os.Args[2])
+	var oldRel, newRel release.Info
+	var ps []plugin.Plugin
+	var inputs []string
+	if *factsFrom != "" {
+		var err error
+		oldRel, newRel, ps, err = plugin.RestoreSnapshot(*factsFrom)
+		internal.MustNoErr(err)
+	} else {
+		inputs = []string{fl.Arg(0), fl.Arg(1)}
+		oldRel, newRel = loadYAML[release.Info](inputs[0]), loadYAML[release.Info](inputs[1])
+		for _, p := range plugin.Registry {
+			p.Load(oldRel, newRel)
+		}
+		if *snapshot == "" {
+			*snapshot = filepath.Join(cfg.GetWorkDir(), "snapshots", newRel.Release, time.Now().Format("2006-01-02T15:04:05"))
+		}
+		internal.MustNoErr(plugin.SaveSnapshot(*snapshot, oldRel, newRel))
+		ps = plugin.Registry
+	}
+
+	newMap := res.ToMap(newRel)
+	newMap["major"] = release.IsMajor(oldRel, newRel)
 	envMap := map[string]any{
 		"releases": map[string]any{
 			"old": res.ToMap(oldRel),
-			"new": res.ToMap(newRel),
+			"new": newMap,
+		},
+		"cfg": map[string]any{
+			"allowedDomains": cfg.GetAllowedDomains(),
 		},
 		"println": fmt.Println,
 		"split":   strings.Split,
 	}
-
-	for _, p := range plugin.Registry {
-		p.Load(oldRel, newRel)
+	for _, p := range ps {
 		p.InitEnv(envMap)
 	}
 
-	runChecks("examples/checks_without_git.yml", envMap)
+	checks := fl.Arg(fl.NArg() - 1)
+	e := evaluator{env: envMap, checks: checks}
+	e.run(checks)
+	rep := release.Report{
+		Product: cfg.GetProjectKey(),
+		Old:     oldRel,
+		New:     newRel,
+		Version: fmt.Sprint(version),
+		Date:    time.Now(),
+		Verdict: release.Verdict(e.results),
+		Checks:  e.results,
+		Facts:   plugin.NumericFacts(envMap),
+	}
+
+	store := history.New(*historyDir)
+	if *factsFrom != "" {
+		// replays are no new evaluations of the release
+		log.Info().Str("snapshot", *factsFrom).Msg("Skipping history of replayed facts")
+	} else {
+		id := internal.Must(store.Add(rep))
+		log.Info().Str("id", id).Msg("Recorded results")
+	}
+	rep.Trends = internal.Must(store.Trends(rep.Product, cfg.GetTrends(), cfg.GetTrendReleases()))
+
+	internal.MustNoErr(os.MkdirAll(*outDir, 0700))
+	for ext, render := range reportFormats {
+		p := filepath.Join(*outDir, "report."+ext)
+		internal.MustNoErr(os.WriteFile(p, internal.Must(render(rep)), 0600))
+		log.Info().Str("path", p).Msg("Wrote report")
+	}
+
+	if *bundle != "" {
+		// copy the inputs, so that appending never writes into their backing array
+		files := append(append([]string{}, inputs...), e.files...)
+		writeBundle(*bundle, *key, rep, envMap, files)
+	}
+
+	if *publish || *dryRun {
+		sum := sha256.Sum256(internal.Must(renderJSON(rep)))
+		opts := plugin.PublishOptions{Digest: hex.EncodeToString(sum[:]), DryRun: *dryRun}
+		internal.MustNoErr(plugin.Publish(ps, rep, opts))
+	}
 }
 
-func runChecks(file string, envMap map[string]any) {
+// evaluator runs the checks of a file and all its imports.
+type evaluator struct {
+	env map[string]any
+	// checks is the file given on the command line, which is imported by "-"
+	checks string
+	// files contains all evaluated files
+	files   []string
+	results []release.Check
+}
+
+func (e *evaluator) run(file string) {
 	if stat, err := os.Stat(file); err != nil || !stat.Mode().IsRegular() {
 		return
 	}
 
+	envMap := e.env
 	env := expr.Env(envMap)
 	cfg := loadYAML[release.Config](file)
+	e.files = append(e.files, file)
 
 	if cfg.Cond == "" {
 		// nothing to do
@@ -92,16 +209,15 @@ func runChecks(file string, envMap map[string]any) {
 		return
 	}
 
-	var cs []release.Check
 	for _, s := range cfg.Steps {
 		if s.Import != "" {
 			fs := internal.Must(filepath.Glob(s.Import))
 			if s.Import == "-" {
-				fs = append(fs, os.Args[3])
+				fs = append(fs, e.checks)
 			}
 			for _, f := range fs {
 				log.Info().Str("file", f).Msg("Importing")
-				runChecks(f, envMap)
+				e.run(f)
 			}
 			continue
 		}
@@ -109,44 +225,51 @@ func runChecks(file string, envMap map[string]any) {
 		fmt.Println("----")
 		log.Info().Str("check", s.Name).Msg("Running")
 
+		var conds []string
 		for _, c := range strings.Split(s.Cond, "\n") {
-			if len(c) == 0 || strings.HasPrefix(c, "#") || strings.HasPrefix(c, "//") {
-				continue
+			if len(c) > 0 && !strings.HasPrefix(c, "#") && !strings.HasPrefix(c, "//") {
+				conds = append(conds, c)
 			}
+		}
 
+		for _, c := range conds {
+			// a step with several conditions yields one check per condition,
+			// which is named after the validated value or the expression
+			name := s.Name
 			if strings.Contains(c, "valid:") {
 				ts := strings.SplitN(c, " ", 3)
 				val, exp := ts[0], ts[2]
+				if len(conds) > 1 {
+					name += ": " + val
+				}
 				m := map[string]any{val: envMap[val]}
 				r, err := govalidator.ValidateMap(m, map[string]any{val: exp})
 				log.WithLevel(toLevel(r)).Str("val", val).Str("rule", exp).Bool("result", r).AnErr("error", err).Msg("Validating")
 				internal.MustNoErr(err)
-				cs = append(cs, release.Check{
-					Name:      s.Name,
+				e.results = append(e.results, release.Check{
+					Name:      name,
+					Type:      s.Type,
 					Status:    release.ToStatus(r),
 					Reference: "",
 					Comment:   strconv.FormatBool(r),
 				})
-			} else if len(c) > 0 {
+			} else {
+				if len(conds) > 1 {
+					name += ": " + c
+				}
 				prg := internal.Must(expr.Compile(c, env))
 				r, err := expr.Run(prg, envMap)
 				log.WithLevel(toLevel(r)).Str("cond", c).Interface("result", r).AnErr("error", err).Msg("Evaluating")
-				cs = append(cs, release.Check{
-					Name:      s.Name,
+				e.results = append(e.results, release.Check{
+					Name:      name,
+					Type:      s.Type,
 					Status:    release.ToStatus(r),
 					Reference: "",
-					Comment:   fmt.Sprint(r),
+					Comment:   secret.Redact(fmt.Sprint(r)),
 				})
 			}
 		}
 	}
-
-	p := filepath.Join("examples", "report.html")
-	f := internal.Must(os.Create(p))
-	defer func() { _ = f.Close() }()
-
-	internal.MustNoErr(writeReport(f, cs))
-	log.Info().Str("path", p).Msg("Wrote report")
 }
 
 func toLevel(ok any) zerolog.Level {
@@ -164,25 +287,3 @@ func loadYAML[T any](uri string) (i T) {
 	internal.MustNoErr(yaml.NewDecoder(r).Decode(&i))
 	return
 }
-
-// writeReport applies the HTML template to variables, including
-//   - all environment variables
-//   - built-in variables such as DATE and HEIMDALL_VERSION
-//   - checks: slices of all checks
-func writeReport(w io.Writer, cs []release.Check) error {
-	repTmplText := internal.Must(fs.ReadFile(heimdall.StaticFS, "plugin/report/template.html"))
-	tmpl := template.New("template.html")
-	tmpl = template.Must(tmpl.Parse(string(repTmplText)))
-
-	data := make(map[string]any)
-	for _, v := range os.Environ() {
-		k, v, _ := strings.Cut(v, "=")
-		data[k] = v
-	}
-	data["HEIMDALL_VERSION"] = version
-	data["PRODUCT_NAME"] = cfg.GetProjectKey()
-	data["DATE"] = time.Now().Format(time.RFC3339)
-	data["checks"] = cs
-
-	return tmpl.Execute(w, data)
-}
@@ -0,0 +1,105 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gschauer/heimdall-dev"
+	"github.com/gschauer/heimdall-dev/cfg"
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/release"
+	"github.com/gschauer/heimdall-dev/secret"
+)
+
+// reportFormats maps the file extension of every report format to its
+// renderer. Registered secrets are redacted from the output of all formats.
+var reportFormats = map[string]func(release.Report) ([]byte, error){
+	"html": renderHTML,
+	"json": renderJSON,
+}
+
+// renderHTML applies the HTML template to variables, including
+//   - environment variables listed in cfg.GetReportEnv
+//   - built-in variables such as DATE and HEIMDALL_VERSION
+//   - checks: slices of all checks
+func renderHTML(r release.Report) ([]byte, error) {
+	repTmplText := internal.Must(fs.ReadFile(heimdall.StaticFS, "plugin/report/template.html"))
+	tmpl := template.New("template.html").Funcs(template.FuncMap{"polyline": polyline})
+	tmpl = template.Must(tmpl.Parse(string(repTmplText)))
+
+	data := make(map[string]any)
+	for _, k := range cfg.GetReportEnv() {
+		if v, ok := os.LookupEnv(k); ok {
+			data[k] = v
+		}
+	}
+	data["HEIMDALL_VERSION"] = r.Version
+	data["PRODUCT_NAME"] = r.Product
+	data["DATE"] = r.Date.Format(time.RFC3339)
+	data["VERDICT"] = r.Verdict
+	data["checks"] = r.Checks
+	data["trends"] = r.Trends
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return []byte(secret.Redact(buf.String())), nil
+}
+
+// Size of trend charts in pixels
+const chartWidth, chartHeight = 300, 80
+
+// polyline returns the points of an SVG polyline, which scales the values to
+// the size of the chart.
+func polyline(ps []release.TrendPoint) string {
+	if len(ps) == 0 {
+		return ""
+	}
+	lo, hi := ps[0].Value, ps[0].Value
+	for _, p := range ps {
+		lo, hi = math.Min(lo, p.Value), math.Max(hi, p.Value)
+	}
+
+	var sb strings.Builder
+	for i, p := range ps {
+		x, y := 0.0, chartHeight/2.0
+		if len(ps) > 1 {
+			x = float64(i) * chartWidth / float64(len(ps)-1)
+		}
+		if hi > lo {
+			y = chartHeight - (p.Value-lo)*chartHeight/(hi-lo)
+		}
+		_, _ = fmt.Fprintf(&sb, "%.1f,%.1f ", x, y)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func renderJSON(r release.Report) ([]byte, error) {
+	bs, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(secret.Redact(string(bs))), nil
+}
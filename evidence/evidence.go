@@ -0,0 +1,235 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Package evidence creates and verifies tamper-evident evidence bundles.
+// A bundle is a gzip-compressed tar archive containing arbitrary files, a
+// manifest with the SHA-256 digests of these files and a signature of the
+// manifest.
+package evidence
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	ManifestFile  = "manifest.json"
+	SignatureFile = "manifest.sig"
+	SignerFile    = "signer.pem"
+)
+
+type Manifest struct {
+	Created time.Time `json:"created"`
+	Release string    `json:"release"`
+	Files   []File    `json:"files"`
+}
+
+type File struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int    `json:"size"`
+}
+
+// Bundle holds the files of an evidence bundle in memory.
+type Bundle struct {
+	Manifest Manifest
+	files    map[string][]byte
+	sig      []byte
+	signer   []byte
+}
+
+// New creates an empty bundle for the given release.
+func New(release string) *Bundle {
+	return &Bundle{
+		Manifest: Manifest{Created: time.Now().UTC(), Release: release},
+		files:    make(map[string][]byte),
+	}
+}
+
+// Add adds a file to the bundle. Adding the same path twice replaces the
+// content of the file.
+func (b *Bundle) Add(path string, data []byte) {
+	b.files[path] = data
+}
+
+// File returns the content of the file with the given path.
+func (b *Bundle) File(path string) ([]byte, bool) {
+	data, ok := b.files[path]
+	return data, ok
+}
+
+// Write computes the manifest, signs it and writes the bundle to w.
+func (b *Bundle) Write(w io.Writer, s *Signer) error {
+	b.Manifest.Files = nil
+	for _, p := range b.paths() {
+		sum := sha256.Sum256(b.files[p])
+		b.Manifest.Files = append(b.Manifest.Files, File{p, hex.EncodeToString(sum[:]), len(b.files[p])})
+	}
+
+	m, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if b.sig, err = s.Sign(m); err != nil {
+		return err
+	}
+	if b.signer, err = s.PEM(); err != nil {
+		return err
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, p := range b.paths() {
+		if err = writeFile(tw, p, b.files[p], b.Manifest.Created); err != nil {
+			return err
+		}
+	}
+	if err = writeFile(tw, ManifestFile, m, b.Manifest.Created); err != nil {
+		return err
+	}
+	if err = writeFile(tw, SignatureFile, b.sig, b.Manifest.Created); err != nil {
+		return err
+	}
+	if err = writeFile(tw, SignerFile, b.signer, b.Manifest.Created); err != nil {
+		return err
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// WriteFile writes the bundle to the file with the given name.
+func (b *Bundle) WriteFile(name string, s *Signer) error {
+	var buf bytes.Buffer
+	if err := b.Write(&buf, s); err != nil {
+		return err
+	}
+	return os.WriteFile(name, buf.Bytes(), 0600)
+}
+
+// Read reads a bundle without verifying it.
+func Read(r io.Reader) (*Bundle, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = gr.Close() }()
+
+	b := &Bundle{files: make(map[string][]byte)}
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+
+		switch h.Name {
+		case ManifestFile:
+			if err = json.Unmarshal(data, &b.Manifest); err != nil {
+				return nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			b.files[h.Name] = data
+		case SignatureFile:
+			b.sig = data
+		case SignerFile:
+			b.signer = data
+		default:
+			b.files[h.Name] = data
+		}
+	}
+	return b, nil
+}
+
+// ReadFile reads the bundle from the file with the given name.
+func ReadFile(name string) (*Bundle, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return Read(f)
+}
+
+// Verify checks the signature of the manifest and the digests of all files.
+// If trusted is empty, the signer embedded in the bundle is used, which only
+// proves the integrity, but not the origin of the bundle. Otherwise, trusted
+// must contain a PEM encoded public key or certificate. In the latter case,
+// the signer certificate may also be issued by the trusted certificate.
+func (b *Bundle) Verify(trusted []byte) error {
+	m, ok := b.files[ManifestFile]
+	if !ok {
+		return errors.New("missing manifest")
+	}
+	if len(b.sig) == 0 || len(b.signer) == 0 {
+		return errors.New("missing signature")
+	}
+	if err := verify(m, b.sig, b.signer, trusted, b.Manifest.Created); err != nil {
+		return err
+	}
+
+	listed := make(map[string]bool)
+	for _, f := range b.Manifest.Files {
+		listed[f.Path] = true
+		data, ok := b.files[f.Path]
+		if !ok {
+			return fmt.Errorf("missing file %s", f.Path)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != f.SHA256 || len(data) != f.Size {
+			return fmt.Errorf("digest mismatch for %s", f.Path)
+		}
+	}
+	for p := range b.files {
+		if p != ManifestFile && !listed[p] {
+			return fmt.Errorf("unlisted file %s", p)
+		}
+	}
+	return nil
+}
+
+func (b *Bundle) paths() (ps []string) {
+	for p := range b.files {
+		if p != ManifestFile {
+			ps = append(ps, p)
+		}
+	}
+	sort.Strings(ps)
+	return
+}
+
+func writeFile(tw *tar.Writer, path string, data []byte, t time.Time) error {
+	h := &tar.Header{Name: path, Mode: 0600, Size: int64(len(data)), ModTime: t}
+	if err := tw.WriteHeader(h); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
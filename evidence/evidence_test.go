@@ -0,0 +1,222 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package evidence
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeSigner writes the private key and the certificates as PEM file and
+// loads it as Signer.
+func writeSigner(t *testing.T, key crypto.Signer, certs ...[]byte) *Signer {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	for _, c := range certs {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c})...)
+	}
+	name := filepath.Join(t.TempDir(), "key.pem")
+	if err = os.WriteFile(name, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSigner(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// newCert creates a certificate for key, which is signed by parent. If parent
+// is nil, the certificate is self-signed.
+func newCert(t *testing.T, cn string, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// roundTrip writes the bundle and reads it again.
+func roundTrip(t *testing.T, b *Bundle, s *Signer) *Bundle {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := b.Write(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func newBundle() *Bundle {
+	b := New("ZZZ 1.4")
+	b.Add("report.json", []byte(`{"verdict":"OK"}`))
+	b.Add("facts/git.json", []byte(`{}`))
+	return b
+}
+
+func TestSignAndVerify(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	tests := []struct {
+		name string
+		key  crypto.Signer
+	}{
+		{"ed25519", edKey},
+		{"ecdsa", ecKey},
+		{"rsa", rsaKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := writeSigner(t, tt.key)
+			b := roundTrip(t, newBundle(), s)
+			if err := b.Verify(nil); err != nil {
+				t.Errorf("Verify() = %v", err)
+			}
+
+			pub, _ := s.PEM()
+			if err := b.Verify(pub); err != nil {
+				t.Errorf("Verify(signer) = %v", err)
+			}
+			if data, _ := b.File("report.json"); string(data) != `{"verdict":"OK"}` {
+				t.Errorf("File() = %q", data)
+			}
+			if got := len(b.Manifest.Files); got != 2 {
+				t.Errorf("manifest lists %d files, want 2", got)
+			}
+		})
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	s := writeSigner(t, key)
+
+	tests := []struct {
+		name   string
+		tamper func(b *Bundle)
+		want   string
+	}{
+		{"changed file", func(b *Bundle) { b.files["report.json"] = []byte(`{"verdict":"FAIL"}`) }, "digest mismatch for report.json"},
+		{"removed file", func(b *Bundle) { delete(b.files, "facts/git.json") }, "missing file facts/git.json"},
+		{"added file", func(b *Bundle) { b.files["extra.txt"] = []byte("x") }, "unlisted file extra.txt"},
+		{"changed manifest", func(b *Bundle) {
+			b.files[ManifestFile] = bytes.Replace(b.files[ManifestFile], []byte("ZZZ"), []byte("YYY"), 1)
+		}, "invalid signature"},
+		{"missing manifest", func(b *Bundle) { delete(b.files, ManifestFile) }, "missing manifest"},
+		{"missing signature", func(b *Bundle) { b.sig = nil }, "missing signature"},
+		{"changed signature", func(b *Bundle) { b.sig[0] ^= 0xff }, "invalid signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := roundTrip(t, newBundle(), s)
+			tt.tamper(b)
+			if err := b.Verify(nil); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Verify() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyTrusted(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca := newCert(t, "CA", caKey, nil, nil)
+	leafKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leaf := newCert(t, "release", leafKey, ca, caKey)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other := newCert(t, "other", otherKey, nil, nil)
+
+	certPEM := func(c *x509.Certificate) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+	}
+	otherPub, _ := writeSigner(t, otherKey).PEM()
+
+	tests := []struct {
+		name    string
+		trusted []byte
+		wantErr bool
+	}{
+		{"embedded signer", nil, false},
+		{"issuer", certPEM(ca), false},
+		{"signer certificate", certPEM(leaf), false},
+		{"other issuer", certPEM(other), true},
+		{"other key", otherPub, true},
+		{"garbage", []byte("not a key"), true},
+	}
+	b := roundTrip(t, newBundle(), writeSigner(t, leafKey, leaf.Raw))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := b.Verify(tt.trusted); (err != nil) != tt.wantErr {
+				t.Errorf("Verify() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadSignerMismatch(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c := newCert(t, "other", otherKey, nil, nil)
+
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	name := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(name, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSigner(name); err == nil {
+		t.Error("LoadSigner() accepted a certificate of another key")
+	}
+}
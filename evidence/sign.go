@@ -0,0 +1,185 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package evidence
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Signer signs the manifest of a bundle with an ed25519, ECDSA or RSA key.
+// If certificates are present, the first one must belong to the key.
+type Signer struct {
+	key   crypto.Signer
+	certs []*x509.Certificate
+}
+
+// LoadSigner reads a PEM file containing a private key and, optionally, the
+// X.509 certificate chain of the key.
+func LoadSigner(name string) (*Signer, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Signer{}
+	for blk, rest := pem.Decode(data); blk != nil; blk, rest = pem.Decode(rest) {
+		var k any
+		switch blk.Type {
+		case "PRIVATE KEY":
+			k, err = x509.ParsePKCS8PrivateKey(blk.Bytes)
+		case "EC PRIVATE KEY":
+			k, err = x509.ParseECPrivateKey(blk.Bytes)
+		case "RSA PRIVATE KEY":
+			k, err = x509.ParsePKCS1PrivateKey(blk.Bytes)
+		case "CERTIFICATE":
+			var c *x509.Certificate
+			c, err = x509.ParseCertificate(blk.Bytes)
+			s.certs = append(s.certs, c)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if k != nil {
+			s.key = k.(crypto.Signer)
+		}
+	}
+
+	if s.key == nil {
+		return nil, fmt.Errorf("%s: no private key found", name)
+	}
+	if len(s.certs) > 0 && !equalKeys(s.certs[0].PublicKey, s.key.Public()) {
+		return nil, fmt.Errorf("%s: certificate does not match private key", name)
+	}
+	return s, nil
+}
+
+// Sign signs the message. ed25519 keys sign the message itself, other keys
+// sign the SHA-256 digest of the message.
+func (s *Signer) Sign(msg []byte) ([]byte, error) {
+	if _, ok := s.key.(ed25519.PrivateKey); ok {
+		return s.key.Sign(rand.Reader, msg, crypto.Hash(0))
+	}
+	h := sha256.Sum256(msg)
+	return s.key.Sign(rand.Reader, h[:], crypto.SHA256)
+}
+
+// PEM returns the certificate chain or, if there is none, the public key.
+func (s *Signer) PEM() ([]byte, error) {
+	if len(s.certs) == 0 {
+		der, err := x509.MarshalPKIXPublicKey(s.key.Public())
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+	}
+
+	var bs []byte
+	for _, c := range s.certs {
+		bs = append(bs, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	return bs, nil
+}
+
+func verify(msg, sig, signer, trusted []byte, t time.Time) error {
+	pub, certs, err := parsePublic(signer)
+	if err != nil {
+		return fmt.Errorf("invalid signer: %w", err)
+	}
+
+	if len(trusted) > 0 {
+		tpub, tcerts, err := parsePublic(trusted)
+		if err != nil {
+			return fmt.Errorf("invalid trusted key: %w", err)
+		}
+
+		if len(certs) > 0 && len(tcerts) > 0 {
+			opts := x509.VerifyOptions{
+				Roots:         x509.NewCertPool(),
+				Intermediates: x509.NewCertPool(),
+				CurrentTime:   t,
+				KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+			}
+			for _, c := range tcerts {
+				opts.Roots.AddCert(c)
+			}
+			for _, c := range certs[1:] {
+				opts.Intermediates.AddCert(c)
+			}
+			if _, err = certs[0].Verify(opts); err != nil {
+				return fmt.Errorf("signer is not trusted: %w", err)
+			}
+		} else if !equalKeys(pub, tpub) {
+			return errors.New("signer is not trusted")
+		}
+	}
+
+	h := sha256.Sum256(msg)
+	ok := false
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, msg, sig)
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(k, h[:], sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], sig) == nil
+	default:
+		return fmt.Errorf("unsupported key type %T", pub)
+	}
+	if !ok {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// parsePublic parses a PEM encoded public key or certificate chain.
+func parsePublic(data []byte) (pub crypto.PublicKey, certs []*x509.Certificate, err error) {
+	for blk, rest := pem.Decode(data); blk != nil; blk, rest = pem.Decode(rest) {
+		switch blk.Type {
+		case "PUBLIC KEY":
+			pub, err = x509.ParsePKIXPublicKey(blk.Bytes)
+		case "CERTIFICATE":
+			var c *x509.Certificate
+			c, err = x509.ParseCertificate(blk.Bytes)
+			certs = append(certs, c)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(certs) > 0 {
+		pub = certs[0].PublicKey
+	}
+	if pub == nil {
+		return nil, nil, errors.New("no public key found")
+	}
+	return pub, certs, nil
+}
+
+func equalKeys(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
@@ -16,5 +16,5 @@ package heimdall
 
 import "embed"
 
-//go:embed plugin/**/*.html
+//go:embed plugin/**/*.html plugin/**/*.md plugin/**/*.txt
 var StaticFS embed.FS
@@ -0,0 +1,180 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Package history records the reports of all evaluations in a directory
+// layout like <dir>/<product>/<release>/<timestamp>.json.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/antonmedv/expr"
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/release"
+	"github.com/gschauer/heimdall-dev/res"
+	"github.com/gschauer/heimdall-dev/secret"
+)
+
+const timeFormat = "2006-01-02T15:04:05"
+
+type Store struct {
+	dir string
+}
+
+// Entry is the summary of a recorded report.
+type Entry struct {
+	ID      string         `json:"id"`
+	Product string         `json:"product"`
+	Release string         `json:"release"`
+	Date    time.Time      `json:"date"`
+	Verdict release.Status `json:"verdict"`
+}
+
+func New(dir string) *Store {
+	return &Store{dir}
+}
+
+// Add records the report and returns its ID.
+func (s *Store) Add(r release.Report) (string, error) {
+	id := filepath.ToSlash(filepath.Join(r.Product, r.New.Release, r.Date.Format(timeFormat)))
+	bs, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	p := filepath.Join(s.dir, filepath.FromSlash(id)+".json")
+	if err = os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return "", err
+	}
+	return id, os.WriteFile(p, []byte(secret.Redact(string(bs))), 0600)
+}
+
+// Get returns the report with the given ID.
+func (s *Store) Get(id string) (r release.Report, err error) {
+	bs, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(id)+".json"))
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(bs, &r)
+	return
+}
+
+// List returns the entries of all reports of the product, sorted by date.
+// If product is empty, the reports of all products are returned.
+func (s *Store) List(product string) ([]Entry, error) {
+	var es []Entry
+	err := s.walk(product, func(id string, r release.Report) error {
+		es = append(es, Entry{id, r.Product, r.New.Release, r.Date, r.Verdict})
+		return nil
+	})
+	return es, err
+}
+
+// Query returns the entries of all reports, for which the expression
+// evaluates to true. The expression can access all fields of a JSON report,
+// e.g. `verdict == "Failed" and facts["junit.tests"] > 0`.
+func (s *Store) Query(product, query string) ([]Entry, error) {
+	prg, err := expr.Compile(query, expr.AsBool())
+	if err != nil {
+		return nil, err
+	}
+
+	var es []Entry
+	err = s.walk(product, func(id string, r release.Report) error {
+		ok, err := expr.Run(prg, res.ToMap(r))
+		if err != nil {
+			return err
+		} else if ok.(bool) {
+			es = append(es, Entry{id, r.Product, r.New.Release, r.Date, r.Verdict})
+		}
+		return nil
+	})
+	return es, err
+}
+
+// Trends evaluates the expressions against the latest report of each of the
+// last n releases of the product, ordered by version. Reports, for which an expression cannot be
+// evaluated, are skipped.
+func (s *Store) Trends(product string, exprs map[string]string, n int) ([]release.Trend, error) {
+	latest := make(map[string]release.Report)
+	err := s.walk(product, func(id string, r release.Report) error {
+		if l, ok := latest[r.New.Release]; !ok || r.Date.After(l.Date) {
+			latest[r.New.Release] = r
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rs := make([]release.Report, 0, len(latest))
+	for _, r := range latest {
+		rs = append(rs, r)
+	}
+	sort.Slice(rs, func(i, j int) bool { return release.CompareVersions(rs[i].New.Release, rs[j].New.Release) < 0 })
+	if len(rs) > n {
+		rs = rs[len(rs)-n:]
+	}
+
+	var ts []release.Trend
+	for _, name := range internal.SortedKeys(exprs) {
+		t := release.Trend{Name: name}
+		for _, r := range rs {
+			v, err := expr.Eval(exprs[name], res.ToMap(r))
+			if f, ok := v.(float64); ok && err == nil {
+				t.Points = append(t.Points, release.TrendPoint{Release: r.New.Release, Value: f})
+			}
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+// walk calls fn for every report of the product in chronological order.
+func (s *Store) walk(product string, fn func(id string, r release.Report) error) error {
+	var ids []string
+	err := filepath.WalkDir(filepath.Join(s.dir, product), func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fs.SkipDir
+		} else if err != nil || d.IsDir() || !strings.HasSuffix(p, ".json") {
+			return err
+		}
+		rel, err := filepath.Rel(s.dir, p)
+		ids = append(ids, filepath.ToSlash(strings.TrimSuffix(rel, ".json")))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// IDs end with the timestamp
+	sort.Slice(ids, func(i, j int) bool { return filepath.Base(ids[i]) < filepath.Base(ids[j]) })
+	for _, id := range ids {
+		r, err := s.Get(id)
+		if err != nil {
+			return err
+		}
+		if err = fn(id, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
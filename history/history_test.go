@@ -0,0 +1,112 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package history
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gschauer/heimdall-dev/release"
+)
+
+func add(t *testing.T, s *Store, rel string, date time.Time, tests float64) {
+	t.Helper()
+	r := release.Report{
+		Product: "ZZZ",
+		New:     release.Info{Name: "ZZZ", Release: rel},
+		Date:    date,
+		Verdict: release.OK,
+		Facts:   map[string]float64{"junit.tests": tests},
+	}
+	if _, err := s.Add(r); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func pt(rel string, v float64) release.TrendPoint {
+	return release.TrendPoint{Release: rel, Value: v}
+}
+
+func TestTrends(t *testing.T) {
+	s := New(t.TempDir())
+	day := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	// recorded out of version order, e.g. a hotfix of an older release
+	add(t, s, "1.10", day, 100)
+	add(t, s, "1.9", day.Add(24*time.Hour), 90)
+	add(t, s, "1.2", day.Add(48*time.Hour), 20)
+	add(t, s, "1.10", day.Add(72*time.Hour), 110)
+	add(t, s, "1.9.1", day.Add(96*time.Hour), 91)
+	add(t, s, "2.0-rc1", day.Add(120*time.Hour), 200)
+
+	exprs := map[string]string{
+		"Tests":   `facts["junit.tests"]`,
+		"Invalid": `facts["junit.tests"] > 0`,
+	}
+	tests := []struct {
+		name string
+		n    int
+		want []release.TrendPoint
+	}{
+		{"all", 10, []release.TrendPoint{pt("1.2", 20), pt("1.9", 90), pt("1.9.1", 91), pt("1.10", 110), pt("2.0-rc1", 200)}},
+		{"last releases", 2, []release.TrendPoint{pt("1.10", 110), pt("2.0-rc1", 200)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, err := s.Trends("ZZZ", exprs, tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// non-numeric results are skipped
+			want := []release.Trend{{Name: "Invalid"}, {Name: "Tests", Points: tt.want}}
+			if !reflect.DeepEqual(ts, want) {
+				t.Errorf("Trends() = %+v, want %+v", ts, want)
+			}
+		})
+	}
+}
+
+func TestTrendsEmpty(t *testing.T) {
+	ts, err := New(t.TempDir()).Trends("ZZZ", map[string]string{"Tests": `facts["junit.tests"]`}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 1 || ts[0].Points != nil {
+		t.Errorf("Trends() = %+v, want no points", ts)
+	}
+}
+
+func TestListAndQuery(t *testing.T) {
+	s := New(t.TempDir())
+	day := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	add(t, s, "1.4", day.Add(time.Hour), 0)
+	add(t, s, "1.3", day, 10)
+
+	es, err := s.List("ZZZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 2 || es[0].ID != "ZZZ/1.3/2023-01-01T12:00:00" || es[1].Release != "1.4" {
+		t.Errorf("List() = %+v, want chronological order", es)
+	}
+
+	es, err = s.Query("", `facts["junit.tests"] > 0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].Release != "1.3" {
+		t.Errorf("Query() = %+v", es)
+	}
+}
@@ -0,0 +1,51 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package internal
+
+import (
+	"regexp"
+	"strings"
+)
+
+// GlobExpr converts a glob pattern into an unanchored regular expression.
+// In contrast to path.Match, "**" matches any characters including "/", and
+// "**/" also matches no directory at all, e.g. "**/*.sql" matches "a.sql".
+// "*" matches any characters except for "/" and "?" a single one.
+func GlobExpr(pattern string) string {
+	var sb strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+// GlobRegexp returns a regular expression, which matches the whole path
+// against the glob pattern, see GlobExpr.
+func GlobRegexp(pattern string) *regexp.Regexp {
+	return regexp.MustCompile("^" + GlobExpr(pattern) + "$")
+}
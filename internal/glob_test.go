@@ -0,0 +1,46 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package internal
+
+import "testing"
+
+func TestGlobRegexp(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"go.mod", "go.mod", true},
+		{"go.mod", "x/go.mod", false},
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "cmd/heimdall-dev/main.go", true},
+		{"db/**", "db/migrations/1.sql", true},
+		{"db/**", "dbx/1.sql", false},
+		{"db/**/*.sql", "db/1.sql", true},
+		{"db/**/*.sql", "db/a/b/1.sql", true},
+		{"release/*", "release/1.x", true},
+		{"release/*", "release/1/x", false},
+		{"v1.?", "v1.4", true},
+		{"v1.?", "v1/4", false},
+		{"v1.?", "v1x4", false},
+		{"a+b(c)", "a+b(c)", true},
+	}
+	for _, tt := range tests {
+		if got := GlobRegexp(tt.pattern).MatchString(tt.path); got != tt.want {
+			t.Errorf("GlobRegexp(%q) matches %q = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/gschauer/heimdall-dev/secret"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -30,7 +31,7 @@ func init() {
 	zerolog.DurationFieldUnit = time.Millisecond
 	zerolog.TimeFieldFormat = "15:04:05"
 	log.Logger = log.Output(zerolog.ConsoleWriter{
-		Out:        os.Stderr,
+		Out:        secret.NewWriter(os.Stderr),
 		NoColor:    false,
 		TimeFormat: zerolog.TimeFieldFormat,
 	})
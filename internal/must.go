@@ -24,6 +24,12 @@ func Must[T any](a T, err error) T {
 	return a
 }
 
+// Must2 is like Must, but for functions returning two values and an error.
+func Must2[T, U any](a T, b U, err error) (T, U) {
+	MustNoErr(err)
+	return a, b
+}
+
 // MustNoErr logs the error message and exits, if the error is non-nil. It is
 // intended for use statements that could return an error and continued
 // execution is not meaningful.
@@ -0,0 +1,182 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Package audit derives facts across the sources of other plugins.
+package audit
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gschauer/heimdall-dev/cfg"
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/plugin"
+	"github.com/gschauer/heimdall-dev/plugin/git"
+	"github.com/gschauer/heimdall-dev/plugin/github"
+	"github.com/gschauer/heimdall-dev/plugin/jira"
+	"github.com/gschauer/heimdall-dev/plugin/scm"
+	"github.com/gschauer/heimdall-dev/release"
+	"github.com/gschauer/heimdall-dev/res"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// FourEyesPlugin checks that no change was authored and approved by the same
+// person. Authors are the Git author and the pull request author of a commit
+// and the assignee of its Jira issues. Approvers are the approvers of its pull
+// requests and the users, who transitioned its Jira issues.
+type FourEyesPlugin struct {
+	// aliases maps every alias to its identity, see cfg.GetAliasFile
+	aliases map[string]string
+}
+
+// Violation is a change, which was authored and approved by the same identity.
+type Violation struct {
+	Commit    string `json:"commit"`
+	Component string `json:"component"`
+	Identity  string `json:"identity"`
+	// Authored and Approved contain the roles of the identity, e.g. "git:author".
+	Authored []string `json:"authored"`
+	Approved []string `json:"approved"`
+	// Links are the URLs of the pull requests and issues of the change.
+	Links []string `json:"links"`
+}
+
+const aliasesFile = "aliases.json"
+
+func init() {
+	plugin.Register(&FourEyesPlugin{})
+}
+
+// Load reads the alias file, which maps every identity to its aliases in
+// Git, GitHub and Jira, e.g. "jane: [jane@acme.org, jdoe, jane.doe]".
+func (p *FourEyesPlugin) Load(o, n release.Info) {
+	p.aliases = make(map[string]string)
+	bs, err := os.ReadFile(cfg.GetAliasFile())
+	if errors.Is(err, os.ErrNotExist) {
+		log.Warn().Str("file", cfg.GetAliasFile()).Msg("Missing alias file")
+		return
+	}
+	internal.MustNoErr(err)
+
+	var ids map[string][]string
+	internal.MustNoErr(yaml.Unmarshal(bs, &ids))
+	for id, as := range ids {
+		for _, a := range append(as, id) {
+			p.aliases[strings.ToLower(a)] = id
+		}
+	}
+}
+
+func (p *FourEyesPlugin) InitEnv(env map[string]any) {
+	vs := p.violations()
+	log.Info().Int("violations", len(vs)).Msg("Checked four-eyes principle")
+	l := make([]any, len(vs))
+	for i, v := range vs {
+		l[i] = res.ToMap(v)
+	}
+	env["audit"] = map[string]any{
+		"fourEyesViolations": l,
+	}
+}
+
+func (p *FourEyesPlugin) Save(dir string) error {
+	return plugin.SaveJSON(dir, aliasesFile, p.aliases)
+}
+
+func (p *FourEyesPlugin) Restore(dir string) error {
+	return plugin.LoadJSON(dir, aliasesFile, &p.aliases)
+}
+
+// identity returns the identity of the alias or the alias itself.
+func (p *FourEyesPlugin) identity(alias string) string {
+	if id, ok := p.aliases[strings.ToLower(alias)]; ok {
+		return id
+	}
+	return strings.ToLower(alias)
+}
+
+// roles collects the roles of all identities involved in a change.
+type roles map[string][]string
+
+func (r roles) add(p *FourEyesPlugin, alias, role string) {
+	if alias != "" {
+		id := p.identity(alias)
+		r[id] = append(r[id], role)
+	}
+}
+
+// violations collects the changes of the Git plugin, their pull requests and
+// Jira issues from the other plugins and checks them.
+func (p *FourEyesPlugin) violations() []Violation {
+	gp, ok := plugin.Find[*git.CommitPlugin]()
+	if !ok {
+		return []Violation{}
+	}
+
+	prs := make(map[string][]*scm.PullRequest)
+	if hp, ok := plugin.Find[*github.RepoPlugin](); ok {
+		prs = hp.PullRequests()
+	}
+	issues := make(map[string]jira.Issue)
+	if jp, ok := plugin.Find[*jira.IssuePlugin](); ok {
+		for _, i := range jp.Issues() {
+			issues[i.Key] = i
+		}
+	}
+	return p.check(gp.Components(), prs, issues)
+}
+
+// check returns the violations of the commits of all components. prs maps
+// commit hashes to pull requests and issues keys to Jira issues.
+func (p *FourEyesPlugin) check(comps map[string]*git.Component, prs map[string][]*scm.PullRequest, issues map[string]jira.Issue) []Violation {
+	vs := []Violation{}
+	for name, comp := range comps {
+		cs := comp.Commits
+		for _, sm := range comp.Submodules {
+			cs = append(cs[:len(cs):len(cs)], sm.Commits...)
+		}
+		for _, c := range cs {
+			authors, approvers := roles{}, roles{}
+			links := []string{}
+			authors.add(p, c.Author.Email, "git:author")
+			for _, pr := range prs[c.Hash] {
+				authors.add(p, pr.Author, "github:author")
+				for _, a := range pr.Approvers {
+					approvers.add(p, a, "github:approver")
+				}
+				links = append(links, pr.URL)
+			}
+			for _, k := range c.JiraKeys {
+				if i, ok := issues[k]; ok {
+					authors.add(p, i.Assignee, "jira:assignee")
+					approvers.add(p, i.Transitioner, "jira:transitioner")
+					links = append(links, i.URL)
+				}
+			}
+
+			for id, rs := range authors {
+				if as, ok := approvers[id]; ok {
+					vs = append(vs, Violation{c.Hash, name, id, rs, as, links})
+				}
+			}
+		}
+	}
+	sort.Slice(vs, func(i, j int) bool {
+		return vs[i].Component < vs[j].Component || (vs[i].Component == vs[j].Component && vs[i].Commit < vs[j].Commit)
+	})
+	return vs
+}
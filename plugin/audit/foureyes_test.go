@@ -0,0 +1,86 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package audit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gschauer/heimdall-dev/plugin/git"
+	"github.com/gschauer/heimdall-dev/plugin/jira"
+	"github.com/gschauer/heimdall-dev/plugin/scm"
+)
+
+func commit(hash, author string, jiraKeys ...string) git.Commit {
+	return git.Commit{Hash: hash, Author: git.Signature{Email: author}, JiraKeys: jiraKeys}
+}
+
+func TestCheck(t *testing.T) {
+	p := &FourEyesPlugin{aliases: map[string]string{
+		"jane@acme.org": "jane", "jdoe": "jane", "jane.doe": "jane", "jane": "jane",
+		"joe@acme.org": "joe", "joseph": "joe", "joe": "joe",
+	}}
+	pr := func(author string, approvers ...string) []*scm.PullRequest {
+		return []*scm.PullRequest{{URL: "https://github.com/org/web/pull/1", Author: author, Approvers: approvers}}
+	}
+	issue := jira.Issue{Key: "ZZZ-1", URL: "https://jira.local/browse/ZZZ-1"}
+
+	tests := []struct {
+		name     string
+		comp     *git.Component
+		prs      []*scm.PullRequest
+		assignee string
+		trans    string
+		want     []Violation
+	}{
+		{"approved by an alias of the author",
+			&git.Component{Commits: []git.Commit{commit("c1", "jane@acme.org")}}, pr("jdoe", "JDoe"), "", "",
+			[]Violation{{"c1", "web", "jane", []string{"git:author", "github:author"}, []string{"github:approver"},
+				[]string{"https://github.com/org/web/pull/1"}}}},
+		{"approved by someone else",
+			&git.Component{Commits: []git.Commit{commit("c1", "Jane@acme.org")}}, pr("jdoe", "joseph"), "", "",
+			[]Violation{}},
+		{"issue transitioned by an alias of the assignee",
+			&git.Component{Commits: []git.Commit{commit("c1", "bob@acme.org", "ZZZ-1")}}, nil, "Jane.Doe", "jdoe",
+			[]Violation{{"c1", "web", "jane", []string{"jira:assignee"}, []string{"jira:transitioner"},
+				[]string{"https://jira.local/browse/ZZZ-1"}}}},
+		{"issue transitioned by someone else",
+			&git.Component{Commits: []git.Commit{commit("c1", "bob@acme.org", "ZZZ-1")}}, nil, "joe", "jane",
+			[]Violation{}},
+		{"unknown alias is an identity of its own",
+			&git.Component{Commits: []git.Commit{commit("c1", "Max@acme.org")}}, pr("max", "max@acme.org"), "", "",
+			[]Violation{{"c1", "web", "max@acme.org", []string{"git:author"}, []string{"github:approver"},
+				[]string{"https://github.com/org/web/pull/1"}}}},
+		{"submodule commit",
+			&git.Component{Submodules: []git.Submodule{{Commits: []git.Commit{commit("c1", "joe@acme.org")}}}},
+			pr("joseph", "joseph"), "", "",
+			[]Violation{{"c1", "web", "joe", []string{"git:author", "github:author"}, []string{"github:approver"},
+				[]string{"https://github.com/org/web/pull/1"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prs := map[string][]*scm.PullRequest{"c1": tt.prs}
+			iss, issues := issue, map[string]jira.Issue{}
+			if tt.assignee != "" {
+				iss.Assignee, iss.Transitioner = tt.assignee, tt.trans
+				issues[iss.Key] = iss
+			}
+			got := p.check(map[string]*git.Component{"web": tt.comp}, prs, issues)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("check() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
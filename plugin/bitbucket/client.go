@@ -0,0 +1,176 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/gschauer/heimdall-dev/cfg"
+	"github.com/rs/zerolog/log"
+)
+
+// client is a minimal client of the Bitbucket Server and Data Center REST
+// API, which authenticates by a personal or repository HTTP access token.
+type client struct {
+	base  *url.URL
+	token string
+	hc    *http.Client
+}
+
+// Error is returned for responses with an unexpected status code.
+type Error struct {
+	URL        string
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("GET %s: %d %s", e.URL, e.StatusCode, e.Message)
+}
+
+// newClient creates a client for the Bitbucket instance at baseURL, which
+// may contain a context path, e.g. "https://code.local/bitbucket".
+func newClient(baseURL, token string) (*client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	return &client{base: u, token: token, hc: http.DefaultClient}, nil
+}
+
+// get decodes the response of the resource at the escaped path, e.g.
+// "rest/api/1.0/projects/KEY/repos/slug", into v. It returns false, if the
+// resource does not exist. Requests exceeding the rate limit are retried.
+func (c *client) get(path string, q url.Values, v any) (bool, error) {
+	u := c.base.String() + "/" + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	for i := 0; ; i++ {
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Accept", "application/json")
+		resp, err := c.hc.Do(req)
+		if err != nil {
+			return false, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return true, json.Unmarshal(body, v)
+		case resp.StatusCode == http.StatusNotFound:
+			return false, nil
+		case resp.StatusCode == http.StatusTooManyRequests && i < cfg.GetBitbucketRetries():
+			wait := rateLimitWait(resp.Header)
+			log.Warn().Str("URL", req.URL.Path).Dur("wait", wait).Msg("Bitbucket rate limit exceeded")
+			time.Sleep(wait)
+		default:
+			var e struct {
+				Errors []struct {
+					Message string `json:"message"`
+				} `json:"errors"`
+			}
+			msg := http.StatusText(resp.StatusCode)
+			if json.Unmarshal(body, &e) == nil && len(e.Errors) > 0 {
+				msg = e.Errors[0].Message
+			}
+			return false, &Error{req.URL.Path, resp.StatusCode, msg}
+		}
+	}
+}
+
+// rateLimitWait returns the duration until the token bucket of the user is
+// refilled, see https://confluence.atlassian.com/bitbucketserver/improving-instance-stability-with-rate-limiting-976171954.html
+func rateLimitWait(h http.Header) time.Duration {
+	if n, err := strconv.Atoi(h.Get("Retry-After")); err == nil {
+		if d := time.Duration(n) * time.Second; d < cfg.GetBitbucketMaxRateLimitWait() {
+			return d
+		}
+		return cfg.GetBitbucketMaxRateLimitWait()
+	}
+	return time.Second
+}
+
+// page is a page of a paged API.
+type page[T any] struct {
+	Values        []T  `json:"values"`
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart"`
+}
+
+// list returns all pages of the paged collection at path.
+func list[T any](c *client, path string, q url.Values) ([]T, bool, error) {
+	if q == nil {
+		q = url.Values{}
+	}
+	q.Set("limit", "100")
+	ts := []T{}
+	for start := 0; ; {
+		q.Set("start", strconv.Itoa(start))
+		var p page[T]
+		if ok, err := c.get(path, q, &p); !ok || err != nil {
+			return ts, ok, err
+		}
+		ts = append(ts, p.Values...)
+		if p.IsLastPage || len(p.Values) == 0 {
+			return ts, true, nil
+		}
+		start = p.NextPageStart
+	}
+}
+
+// repoPath returns the project key and the slug of a repository, e.g. "PROJ"
+// and "zzz-web" for "https://code.local/scm/proj/zzz-web.git" or
+// "ssh://git@code.local:7999/proj/zzz-web.git". Repositories on other hosts
+// than the Bitbucket instance are skipped.
+func (c *client) repoPath(u string) (string, string, bool) {
+	ep, err := transport.NewEndpoint(u)
+	if err != nil || ep.Protocol == "file" || !strings.EqualFold(ep.Host, c.base.Hostname()) {
+		return "", "", false
+	}
+	p := strings.Trim(ep.Path, "/")
+	if ep.Protocol == "http" || ep.Protocol == "https" {
+		p = strings.TrimPrefix(strings.TrimPrefix(p, strings.Trim(c.base.Path, "/")), "/")
+	}
+	ps := strings.Split(strings.TrimSuffix(strings.TrimPrefix(p, "scm/"), ".git"), "/")
+	switch {
+	case len(ps) == 2:
+		return strings.ToUpper(ps[0]), ps[1], true
+	case len(ps) >= 4 && ps[0] == "projects" && ps[2] == "repos":
+		return strings.ToUpper(ps[1]), ps[3], true
+	}
+	return "", "", false
+}
+
+// repoAPI returns the path of the repository in the core REST API.
+func repoAPI(project, slug string) string {
+	return fmt.Sprintf("rest/api/1.0/projects/%s/repos/%s", url.PathEscape(project), url.PathEscape(slug))
+}
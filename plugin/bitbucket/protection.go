@@ -0,0 +1,178 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package bitbucket
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/plugin/scm"
+	"github.com/rs/zerolog/log"
+)
+
+// refMatcher selects the branches of a branch permission, a required build
+// or a default reviewer condition.
+type refMatcher struct {
+	ID        string `json:"id"`
+	DisplayID string `json:"displayId"`
+	Type      struct {
+		ID string `json:"id"`
+	} `json:"type"`
+}
+
+// matches returns true, if the matcher selects the branch. Branching model
+// categories, e.g. "production", are not resolved.
+func (m *refMatcher) matches(branch string) bool {
+	switch m.Type.ID {
+	case "ANY_REF":
+		return true
+	case "BRANCH":
+		return m.ID == "refs/heads/"+branch || m.DisplayID == branch
+	case "PATTERN":
+		// patterns match the branch or the full ref and are case-insensitive
+		re := patternRegexp(m.ID)
+		return re.MatchString(branch) || re.MatchString("refs/heads/"+branch)
+	}
+	return false
+}
+
+// patternRegexp converts an Ant-style pattern into a case-insensitive regular
+// expression, see internal.GlobExpr.
+func patternRegexp(pattern string) *regexp.Regexp {
+	return regexp.MustCompile("(?i)^" + internal.GlobExpr(pattern) + "$")
+}
+
+// restriction is a branch permission, see
+// https://confluence.atlassian.com/bitbucketserver/using-branch-permissions-776639807.html
+type restriction struct {
+	ID      int        `json:"id"`
+	Type    string     `json:"type"`
+	Matcher refMatcher `json:"matcher"`
+	// Users, Groups and AccessKeys are exempted from the restriction.
+	Users      []any `json:"users"`
+	Groups     []any `json:"groups"`
+	AccessKeys []any `json:"accessKeys"`
+}
+
+// repoPath returns the path of a Bitbucket repository, e.g. "PROJECT/slug".
+func (p *RepoPlugin) repoPath(u string) (string, bool) {
+	project, slug, ok := p.client.repoPath(u)
+	return project + "/" + slug, ok
+}
+
+// branchExists reports whether the branch exists in the repository.
+func (p *RepoPlugin) branchExists(repo, branch string) bool {
+	project, slug, _ := strings.Cut(repo, "/")
+	q := url.Values{"filterText": {branch}}
+	bs, _ := internal.Must2(list[struct {
+		DisplayID string `json:"displayId"`
+	}](p.client, repoAPI(project, slug)+"/branches", q))
+	for _, b := range bs {
+		if b.DisplayID == branch {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultBranch returns the default branch of the repository.
+func (p *RepoPlugin) defaultBranch(repo string) string {
+	return p.repoInfos[repo].DefaultBranch
+}
+
+// loadProtection derives the protection of a branch from the branch
+// permissions, required builds, default reviewers and pull request settings.
+// Administrators are subject to branch permissions unless they are exempted.
+func (p *RepoPlugin) loadProtection(project, slug, branch string) scm.BranchProtection {
+	log.Info().Str("repo", project+"/"+slug).Str("branch", branch).Msg("Loading branch permissions")
+	bp := scm.BranchProtection{Repo: project + "/" + slug, Branch: branch, RequiredChecks: []string{}, Rulesets: []string{},
+		ForcePushAllowed: true, DeletionAllowed: true}
+	s := p.settings[project+"/"+slug]
+
+	u := fmt.Sprintf("rest/branch-permissions/2.0/projects/%s/repos/%s/restrictions", url.PathEscape(project), url.PathEscape(slug))
+	rs, _ := internal.Must2(list[restriction](p.client, u, nil))
+	bp.EnforceAdmins = true
+	prOnly := false
+	for _, r := range rs {
+		if !r.Matcher.matches(branch) {
+			continue
+		}
+		bp.Protected = true
+		bp.EnforceAdmins = bp.EnforceAdmins && len(r.Users) == 0 && len(r.Groups) == 0 && len(r.AccessKeys) == 0
+		switch r.Type {
+		case "pull-request-only", "read-only":
+			prOnly = true
+			bp.RequiredReviews = internal.Max(bp.RequiredReviews, s.RequiredApprovers)
+			bp.ForcePushAllowed = false
+		case "fast-forward-only":
+			bp.ForcePushAllowed = false
+		case "no-deletes":
+			bp.DeletionAllowed = false
+		}
+	}
+	bp.EnforceAdmins = bp.EnforceAdmins && bp.Protected
+	bp.DismissStaleReviews = s.UnapproveOnUpdate
+	bp.LinearHistory = linearHistory(s)
+
+	// approvals of default reviewers only count, if changes require a pull request
+	u = fmt.Sprintf("rest/default-reviewers/1.0/projects/%s/repos/%s/conditions", url.PathEscape(project), url.PathEscape(slug))
+	var conds []struct {
+		TargetRefMatcher  refMatcher `json:"targetRefMatcher"`
+		RequiredApprovals int        `json:"requiredApprovals"`
+	}
+	internal.Must(p.client.get(u, nil, &conds))
+	for _, c := range conds {
+		if prOnly && c.TargetRefMatcher.matches(branch) {
+			bp.RequiredReviews = internal.Max(bp.RequiredReviews, c.RequiredApprovals)
+		}
+	}
+
+	// required builds were added in Bitbucket 7.14
+	u = fmt.Sprintf("rest/required-builds/latest/projects/%s/repos/%s/conditions", url.PathEscape(project), url.PathEscape(slug))
+	bs, _ := internal.Must2(list[struct {
+		BuildParentKeys []string   `json:"buildParentKeys"`
+		RefMatcher      refMatcher `json:"refMatcher"`
+	}](p.client, u, nil))
+	for _, b := range bs {
+		if !b.RefMatcher.matches(branch) {
+			continue
+		}
+		for _, k := range b.BuildParentKeys {
+			if !internal.Contains(bp.RequiredChecks, k) {
+				bp.RequiredChecks = append(bp.RequiredChecks, k)
+			}
+		}
+	}
+	return bp
+}
+
+// linearHistory returns true, if all enabled merge strategies avoid merge
+// commits.
+func linearHistory(s prSettings) bool {
+	enabled := 0
+	for _, st := range s.MergeConfig.Strategies {
+		if !st.Enabled {
+			continue
+		}
+		enabled++
+		if st.ID != "ff-only" && st.ID != "rebase-ff-only" && st.ID != "squash" && st.ID != "squash-ff-only" {
+			return false
+		}
+	}
+	return enabled > 0
+}
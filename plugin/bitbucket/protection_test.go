@@ -0,0 +1,45 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package bitbucket
+
+import "testing"
+
+func TestRefMatcher(t *testing.T) {
+	tests := []struct {
+		typ, id, branch string
+		want            bool
+	}{
+		{"ANY_REF", "", "main", true},
+		{"BRANCH", "refs/heads/main", "main", true},
+		{"BRANCH", "refs/heads/main", "release/main", false},
+		{"PATTERN", "release/**", "release/a/b", true},
+		{"PATTERN", "release/**", "release/1.x", true},
+		{"PATTERN", "release/*", "release/a/b", false},
+		{"PATTERN", "release/*", "Release/1.x", true},
+		{"PATTERN", "**/hotfix", "team/a/hotfix", true},
+		{"PATTERN", "**/hotfix", "hotfix", true},
+		{"PATTERN", "refs/heads/**", "feature/x", true},
+		{"PATTERN", "v1.?", "v1.4", true},
+		{"PATTERN", "v1.?", "v1/4", false},
+		{"PATTERN", "main", "main2", false},
+	}
+	for _, tt := range tests {
+		m := refMatcher{ID: tt.id}
+		m.Type.ID = tt.typ
+		if got := m.matches(tt.branch); got != tt.want {
+			t.Errorf("%s %q matches(%q) = %v, want %v", tt.typ, tt.id, tt.branch, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package bitbucket
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/plugin"
+	"github.com/gschauer/heimdall-dev/plugin/git"
+	"github.com/gschauer/heimdall-dev/plugin/scm"
+	"github.com/rs/zerolog/log"
+)
+
+// BuildStatus is a build result reported for an evaluated commit.
+type BuildStatus struct {
+	Component string `json:"component"`
+	Repo      string `json:"repo"`
+	Hash      string `json:"hash"`
+	Key       string `json:"key"`
+	Name      string `json:"name"`
+	// State is "success", "failure" or "pending" like GitHub commit statuses.
+	State string    `json:"state"`
+	URL   string    `json:"url"`
+	Date  time.Time `json:"date"`
+}
+
+// provenance contains the pull requests of all commits in the release range.
+type provenance struct {
+	Commits      []scm.CommitProvenance `json:"commits"`
+	PullRequests []*scm.PullRequest     `json:"pullRequests"`
+}
+
+type user struct {
+	Name string `json:"name"`
+}
+
+type ref struct {
+	DisplayID    string `json:"displayId"`
+	LatestCommit string `json:"latestCommit"`
+}
+
+// pullRequest is a pull request, see
+// https://developer.atlassian.com/server/bitbucket/rest/v811/api-group-pull-requests/
+type pullRequest struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	Author struct {
+		User user `json:"user"`
+	} `json:"author"`
+	Reviewers []struct {
+		User               user   `json:"user"`
+		Status             string `json:"status"`
+		LastReviewedCommit string `json:"lastReviewedCommit"`
+	} `json:"reviewers"`
+	FromRef ref `json:"fromRef"`
+	ToRef   ref `json:"toRef"`
+	Links   struct {
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+// loadProvenance maps the commits of the Git plugin to their pull requests.
+func (p *RepoPlugin) loadProvenance() {
+	gp, ok := plugin.Find[*git.CommitPlugin]()
+	if !ok {
+		log.Warn().Msg("Skipping pull requests without Git plugin")
+		return
+	}
+
+	prs := make(map[string]*scm.PullRequest)
+	for name, c := range gp.Components() {
+		p.loadCommits(name, c.URL, c.Commits, prs)
+		for _, sm := range c.Submodules {
+			p.loadCommits(name, sm.URL, sm.Commits, prs)
+		}
+	}
+	for _, pr := range prs {
+		p.prov.PullRequests = append(p.prov.PullRequests, pr)
+	}
+	sort.Slice(p.prov.PullRequests, func(i, j int) bool {
+		a, b := p.prov.PullRequests[i], p.prov.PullRequests[j]
+		return a.Repo < b.Repo || (a.Repo == b.Repo && a.Number < b.Number)
+	})
+}
+
+func (p *RepoPlugin) loadCommits(comp, u string, cs []git.Commit, prs map[string]*scm.PullRequest) {
+	project, slug, ok := p.client.repoPath(u)
+	if !ok {
+		return
+	}
+	repo := project + "/" + slug
+	inPR := make(map[string]string)
+	mergeCommits := make(map[string]string)
+	for _, c := range cs {
+		cp := scm.CommitProvenance{Hash: c.Hash, Component: comp, Repo: repo, PullRequests: []int{}}
+		ls, _ := internal.Must2(list[pullRequest](p.client, repoAPI(project, slug)+"/commits/"+c.Hash+"/pull-requests", nil))
+		for _, pr := range ls {
+			if pr.State != "MERGED" {
+				continue
+			}
+			key := prKey(repo, pr.ID)
+			if prs[key] == nil {
+				prs[key], mergeCommits[key] = p.loadPullRequest(project, slug, pr)
+			}
+			cp.PullRequests = append(cp.PullRequests, pr.ID)
+			inPR[c.Hash] = key
+		}
+		p.prov.Commits = append(p.prov.Commits, cp)
+	}
+
+	// the merge method is derived from the merge commit like on GitHub, since
+	// the merge strategy is not recorded either
+	for _, c := range cs {
+		key, ok := inPR[c.Hash]
+		if !ok || mergeCommits[key] != c.Hash {
+			continue
+		}
+		switch {
+		case len(c.Parents) > 1:
+			prs[key].MergeMethod = scm.Merge
+		case len(c.Parents) == 1 && inPR[c.Parents[0]] == key:
+			prs[key].MergeMethod = scm.Rebase
+		default:
+			prs[key].MergeMethod = scm.Squash
+		}
+	}
+}
+
+// loadPullRequest returns the pull request and its merge commit.
+func (p *RepoPlugin) loadPullRequest(project, slug string, pr pullRequest) (*scm.PullRequest, string) {
+	log.Debug().Str("repo", project+"/"+slug).Int("number", pr.ID).Msg("Loading pull request")
+	res := &scm.PullRequest{
+		Repo:       project + "/" + slug,
+		Number:     pr.ID,
+		Title:      pr.Title,
+		Author:     pr.Author.User.Name,
+		BaseBranch: pr.ToRef.DisplayID,
+		HeadSHA:    pr.FromRef.LatestCommit,
+	}
+	if len(pr.Links.Self) > 0 {
+		res.URL = pr.Links.Self[0].Href
+	}
+
+	res.Approvers, res.StaleApprovers = []string{}, []string{}
+	for _, r := range pr.Reviewers {
+		if r.Status != "APPROVED" || r.User.Name == res.Author {
+			continue
+		}
+		res.Approvers = append(res.Approvers, r.User.Name)
+		if r.LastReviewedCommit != "" && r.LastReviewedCommit != res.HeadSHA {
+			res.StaleApprovers = append(res.StaleApprovers, r.User.Name)
+		}
+	}
+	sort.Strings(res.Approvers)
+	sort.Strings(res.StaleApprovers)
+
+	var mergeCommit string
+	u := fmt.Sprintf("%s/pull-requests/%d/activities", repoAPI(project, slug), pr.ID)
+	as, _ := internal.Must2(list[struct {
+		Action string `json:"action"`
+		User   user   `json:"user"`
+		Commit struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}](p.client, u, nil))
+	for _, a := range as {
+		if a.Action == "MERGED" {
+			res.MergedBy, mergeCommit = a.User.Name, a.Commit.ID
+			break
+		}
+	}
+
+	res.RequiredChecks, res.ChecksPassed = p.requiredChecks(project, slug, res.BaseBranch, res.HeadSHA)
+	return res, mergeCommit
+}
+
+// requiredChecks returns the state of all builds of the commit, which are
+// required for merging into the branch.
+func (p *RepoPlugin) requiredChecks(project, slug, branch, sha string) ([]scm.StatusCheck, bool) {
+	key := project + "/" + slug + ":" + branch
+	names, ok := p.required[key]
+	if !ok {
+		names = p.loadProtection(project, slug, branch).RequiredChecks
+		p.required[key] = names
+	}
+
+	states := make(map[string]string)
+	for _, s := range p.buildStatuses(sha) {
+		states[s.Key] = s.State
+	}
+	checks, passed := make([]scm.StatusCheck, 0, len(names)), true
+	for _, n := range names {
+		s, ok := states[n]
+		if !ok {
+			s = "missing"
+		}
+		checks = append(checks, scm.StatusCheck{Name: n, State: s})
+		passed = passed && s == "success"
+	}
+	return checks, passed
+}
+
+// loadBuildStatuses loads the build statuses of the evaluated commit of every
+// component.
+func (p *RepoPlugin) loadBuildStatuses() {
+	gp, ok := plugin.Find[*git.CommitPlugin]()
+	if !ok {
+		log.Warn().Msg("Skipping build statuses without Git plugin")
+		return
+	}
+	names := make([]string, 0, len(gp.Components()))
+	for name := range gp.Components() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	p.statuses = []BuildStatus{}
+	for _, name := range names {
+		c := gp.Components()[name]
+		project, slug, ok := p.client.repoPath(c.URL)
+		if !ok {
+			continue
+		}
+		log.Info().Str("component", name).Str("hash", c.Hash).Msg("Loading build statuses")
+		for _, s := range p.buildStatuses(c.Hash) {
+			s.Component, s.Repo, s.Hash = name, project+"/"+slug, c.Hash
+			p.statuses = append(p.statuses, s)
+		}
+	}
+}
+
+// buildStatuses returns the latest status of every build of a commit.
+func (p *RepoPlugin) buildStatuses(sha string) []BuildStatus {
+	ss, _ := internal.Must2(list[struct {
+		State     string `json:"state"`
+		Key       string `json:"key"`
+		Name      string `json:"name"`
+		URL       string `json:"url"`
+		DateAdded int64  `json:"dateAdded"`
+	}](p.client, "rest/build-status/1.0/commits/"+url.PathEscape(sha), nil))
+
+	bs := []BuildStatus{}
+	for _, s := range ss {
+		state := "pending"
+		switch strings.ToUpper(s.State) {
+		case "SUCCESSFUL":
+			state = "success"
+		case "FAILED":
+			state = "failure"
+		}
+		bs = append(bs, BuildStatus{Key: s.Key, Name: s.Name, State: state, URL: s.URL, Date: time.UnixMilli(s.DateAdded).UTC()})
+	}
+	return bs
+}
+
+func prKey(repo string, n int) string {
+	return fmt.Sprintf("%s#%d", repo, n)
+}
@@ -0,0 +1,256 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Package bitbucket loads the facts of repositories hosted on Bitbucket Server
+// or Data Center. The facts have the same shape as those of the GitHub plugin,
+// so that policies can be evaluated against either host.
+package bitbucket
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/plugin"
+	"github.com/gschauer/heimdall-dev/plugin/scm"
+	"github.com/gschauer/heimdall-dev/release"
+	"github.com/gschauer/heimdall-dev/res"
+	"github.com/gschauer/heimdall-dev/secret"
+	"github.com/rs/zerolog/log"
+)
+
+// RepoInfo contains the settings of a repository, which are named like the
+// settings of GitHub repositories.
+type RepoInfo struct {
+	ID            int64   `json:"ID"`
+	Name          string  `json:"name"`
+	FullName      string  `json:"full_name"`
+	DefaultBranch string  `json:"default_branch,omitempty"`
+	GitURL        string  `json:"git_url,omitempty"`
+	GitCloneURL   string  `json:"git_clone_url,omitempty"`
+	PRRules       PRRules `json:"required_pull_request_reviews"`
+}
+
+// PRRules are the approvals required by the pull request settings.
+type PRRules struct {
+	RequiredApprovingReviewCount int  `json:"required_approving_review_count"`
+	DismissStaleReviews          bool `json:"dismiss_stale_reviews"`
+}
+
+// RepoPlugin loads the facts of the Bitbucket repositories of all components,
+// which are hosted on the Bitbucket instance BITBUCKET_URL.
+type RepoPlugin struct {
+	client    *client
+	repoInfos map[string]RepoInfo
+	settings  map[string]prSettings
+	// required builds by "PROJECT/slug:branch"
+	required    map[string][]string
+	protections []scm.BranchProtection
+	statuses    []BuildStatus
+	prov        provenance
+}
+
+const (
+	reposFile       = "repos.json"
+	pullsFile       = "pulls.json"
+	protectionsFile = "protections.json"
+	statusesFile    = "statuses.json"
+)
+
+// prSettings are the pull request settings of a repository, see
+// https://developer.atlassian.com/server/bitbucket/rest/v811/api-group-repository/#api-api-latest-projects-projectkey-repos-repositoryslug-settings-pull-requests-get
+type prSettings struct {
+	RequiredApprovers int  `json:"requiredApprovers"`
+	UnapproveOnUpdate bool `json:"unapproveOnUpdate"`
+	MergeConfig       struct {
+		Strategies []struct {
+			ID      string `json:"id"`
+			Enabled bool   `json:"enabled"`
+		} `json:"strategies"`
+	} `json:"mergeConfig"`
+}
+
+func (p *RepoPlugin) Load(o, n release.Info) {
+	p.repoInfos = make(map[string]RepoInfo)
+	p.settings = make(map[string]prSettings)
+	p.required = make(map[string][]string)
+	for _, c := range n.Components {
+		u, _ := res.SplitRev(c)
+		u, _ = res.SplitPath(u)
+		project, slug, ok := p.client.repoPath(u)
+		if !ok {
+			log.Debug().Str("url", u).Msg("Skipping non-Bitbucket repository")
+			continue
+		}
+		// components of a monorepo share the repository
+		if _, ok := p.repoInfos[project+"/"+slug]; !ok {
+			p.repoInfos[project+"/"+slug] = p.loadRepo(project, slug)
+		}
+	}
+	for _, rb := range scm.ReleaseBranches(n.Components, p.repoPath, p.branchExists, p.defaultBranch) {
+		project, slug, _ := strings.Cut(rb.Repo, "/")
+		bp := p.loadProtection(project, slug, rb.Branch)
+		p.protections = append(p.protections, bp)
+		p.required[rb.Repo+":"+rb.Branch] = bp.RequiredChecks
+	}
+	p.loadProvenance()
+	p.loadBuildStatuses()
+}
+
+func (p *RepoPlugin) InitEnv(env map[string]any) {
+	names := make([]string, 0, len(p.repoInfos))
+	for k := range p.repoInfos {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	repos, allRepos := make(map[string]any), []any{}
+	for _, k := range names {
+		m := res.ToMap(p.repoInfos[k])
+		repos[k] = m
+		allRepos = append(allRepos, m)
+	}
+
+	prs := make(map[string]any)
+	pulls := []any{}
+	for _, pr := range p.prov.PullRequests {
+		m := res.ToMap(pr)
+		prs[prKey(pr.Repo, pr.Number)] = m
+		pulls = append(pulls, m)
+	}
+	commits := []any{}
+	for _, c := range p.prov.Commits {
+		m := res.ToMap(c)
+		l := []any{}
+		for _, n := range c.PullRequests {
+			l = append(l, prs[prKey(c.Repo, n)])
+		}
+		m["pullRequests"] = l
+		commits = append(commits, m)
+	}
+
+	env["bitbucket"] = map[string]any{
+		// repositories by "PROJECT/slug"
+		"repos":    repos,
+		"allRepos": allRepos,
+		// commits of the release with their merged pull requests
+		"commits":      commits,
+		"pullRequests": pulls,
+		// protection of the released branches by the branch permissions
+		"branchProtections": toMaps(p.protections),
+		// build statuses of the evaluated commits
+		"buildStatuses": toMaps(p.statuses),
+	}
+}
+
+func (p *RepoPlugin) Save(dir string) error {
+	for f, v := range map[string]any{
+		reposFile:       p.repoInfos,
+		protectionsFile: p.protections,
+		pullsFile:       p.prov,
+		statusesFile:    p.statuses,
+	} {
+		if err := plugin.SaveJSON(dir, f, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *RepoPlugin) Restore(dir string) error {
+	for f, v := range map[string]any{
+		reposFile:       &p.repoInfos,
+		protectionsFile: &p.protections,
+		pullsFile:       &p.prov,
+		statusesFile:    &p.statuses,
+	} {
+		if err := plugin.LoadJSON(dir, f, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadRepo loads the settings of a repository. The full name is
+// "PROJECT/slug", and the required approvals are taken from the pull request
+// settings.
+func (p *RepoPlugin) loadRepo(project, slug string) RepoInfo {
+	log.Info().Str("repo", project+"/"+slug).Msg("Loading Bitbucket repository")
+	var r struct {
+		ID    int64  `json:"id"`
+		Slug  string `json:"slug"`
+		Name  string `json:"name"`
+		Links struct {
+			Clone []struct {
+				Href string `json:"href"`
+				Name string `json:"name"`
+			} `json:"clone"`
+		} `json:"links"`
+	}
+	ok := internal.Must(p.client.get(repoAPI(project, slug), nil, &r))
+	internal.MustOkMsgf(r, ok, "Bitbucket repository %s/%s not found", project, slug)
+
+	var b struct {
+		DisplayID string `json:"displayId"`
+	}
+	internal.Must(p.client.get(repoAPI(project, slug)+"/default-branch", nil, &b))
+	var s prSettings
+	internal.Must(p.client.get(repoAPI(project, slug)+"/settings/pull-requests", nil, &s))
+	p.settings[project+"/"+slug] = s
+
+	info := RepoInfo{
+		ID:            r.ID,
+		Name:          r.Slug,
+		FullName:      project + "/" + r.Slug,
+		DefaultBranch: b.DisplayID,
+	}
+	for _, l := range r.Links.Clone {
+		if l.Name == "ssh" {
+			info.GitURL = l.Href
+		} else {
+			info.GitCloneURL = l.Href
+		}
+	}
+	info.PRRules.RequiredApprovingReviewCount = s.RequiredApprovers
+	info.PRRules.DismissStaleReviews = s.UnapproveOnUpdate
+	return info
+}
+
+func toMaps[T any](ts []T) []any {
+	l := []any{}
+	for _, t := range ts {
+		l = append(l, res.ToMap(t))
+	}
+	return l
+}
+
+func init() {
+	baseURL := os.Getenv("BITBUCKET_URL")
+	if baseURL == "" {
+		log.Warn().Str("name", "BITBUCKET_URL").Msg("undefined environment variable")
+		plugin.RegisterOffline(&RepoPlugin{})
+		return
+	}
+
+	token := secret.Getenv("BITBUCKET_TOKEN")
+	if token == "" {
+		log.Warn().Str("name", "BITBUCKET_TOKEN").Msg("undefined environment variable")
+		plugin.RegisterOffline(&RepoPlugin{})
+		return
+	}
+
+	c, err := newClient(baseURL, token)
+	internal.MustNoErr(err)
+	plugin.Register(&RepoPlugin{client: c})
+}
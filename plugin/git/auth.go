@@ -0,0 +1,93 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/gschauer/heimdall-dev/secret"
+	"github.com/rs/zerolog/log"
+)
+
+// auth returns the authentication method for the repository URL.
+// Credentials are configured per host by environment variables with the
+// upper-case host name as suffix, e.g. GIT_TOKEN_CODE_LOCAL for code.local.
+// Variables without suffix apply to all hosts:
+//   - GIT_TOKEN: access token for HTTPS
+//   - GIT_USERNAME, GIT_PASSWORD: basic authentication for HTTPS
+//   - GIT_SSH_KEY, GIT_SSH_KEY_PASSWORD: private key file for SSH
+//
+// SSH falls back to the SSH agent, and local repositories as well as HTTPS
+// without credentials are accessed anonymously.
+func auth(ep *transport.Endpoint) (transport.AuthMethod, error) {
+	switch ep.Protocol {
+	case "file":
+		return nil, nil
+	case "ssh":
+		user := ep.User
+		if user == "" {
+			user = "git"
+		}
+		if key := getenv("GIT_SSH_KEY", ep.Host); key != "" {
+			return ssh.NewPublicKeysFromFile(user, key, secretenv("GIT_SSH_KEY_PASSWORD", ep.Host))
+		}
+		log.Debug().Str("host", ep.Host).Msg("Using SSH agent")
+		return ssh.NewSSHAgentAuth(user)
+	default:
+		user := getenv("GIT_USERNAME", ep.Host)
+		if token := secretenv("GIT_TOKEN", ep.Host); token != "" {
+			if user == "" {
+				user = "x-access-token"
+			}
+			return &http.BasicAuth{Username: user, Password: token}, nil
+		}
+		if pass := secretenv("GIT_PASSWORD", ep.Host); user != "" && pass != "" {
+			return &http.BasicAuth{Username: user, Password: pass}, nil
+		}
+		log.Debug().Str("host", ep.Host).Msg("Using anonymous access")
+		return nil, nil
+	}
+}
+
+// getenv returns the host-specific value of the environment variable or,
+// if undefined, the global one.
+func getenv(name, host string) string {
+	if v, ok := os.LookupEnv(name + "_" + hostSuffix(host)); ok {
+		return v
+	}
+	return os.Getenv(name)
+}
+
+// secretenv is like getenv, but registers the value as secret.
+func secretenv(name, host string) string {
+	v := getenv(name, host)
+	secret.Register(v)
+	return v
+}
+
+func hostSuffix(host string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		} else if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, host)
+}
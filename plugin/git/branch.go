@@ -0,0 +1,181 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	fgraph "github.com/go-git/go-git/v5/plumbing/format/commitgraph"
+	"github.com/go-git/go-git/v5/plumbing/object/commitgraph"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/rs/zerolog/log"
+)
+
+// nodeIndex returns an index of commit nodes, which uses the commit-graph
+// file of the repository if available.
+func nodeIndex(r *git.Repository) commitgraph.CommitNodeIndex {
+	if s, ok := r.Storer.(*filesystem.Storage); ok {
+		if f, err := s.Filesystem().Open("objects/info/commit-graph"); err == nil {
+			if idx, err := fgraph.OpenFileIndex(f); err == nil {
+				log.Debug().Msg("Using commit-graph")
+				return commitgraph.NewGraphCommitNodeIndex(idx, r.Storer)
+			}
+		}
+	}
+	return commitgraph.NewObjectCommitNodeIndex(r.Storer)
+}
+
+// containment checks whether commits contain the target commit.
+// The results are shared across all checks, so that every commit is visited
+// at most once. Commits with a lower generation than the target are not
+// visited at all.
+type containment struct {
+	idx    commitgraph.CommitNodeIndex
+	target commitgraph.CommitNode
+	memo   map[plumbing.Hash]bool
+}
+
+type frame struct {
+	hash    plumbing.Hash
+	parents []plumbing.Hash
+	i       int
+}
+
+func newContainment(r *git.Repository, target plumbing.Hash) (*containment, error) {
+	idx := nodeIndex(r)
+	n, err := idx.Get(target)
+	if err != nil {
+		return nil, err
+	}
+	return &containment{idx, n, map[plumbing.Hash]bool{target: true}}, nil
+}
+
+// contains walks the history of tip iteratively, i.e., deep histories do not
+// overflow the stack.
+func (c *containment) contains(tip plumbing.Hash) (bool, error) {
+	var stack []frame
+	push := func(h plumbing.Hash) error {
+		n, err := c.idx.Get(h)
+		if err != nil {
+			return err
+		}
+		if n.Generation() < c.target.Generation() {
+			c.memo[h] = false
+		} else {
+			stack = append(stack, frame{hash: h, parents: n.ParentHashes()})
+		}
+		return nil
+	}
+
+	if _, ok := c.memo[tip]; !ok {
+		if err := push(tip); err != nil {
+			return false, err
+		}
+	}
+	for len(stack) > 0 {
+		f := &stack[len(stack)-1]
+		if f.i > 0 && c.memo[f.parents[f.i-1]] {
+			c.memo[f.hash] = true
+			stack = stack[:len(stack)-1]
+			continue
+		} else if f.i == len(f.parents) {
+			c.memo[f.hash] = false
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		p := f.parents[f.i]
+		f.i++
+		if _, ok := c.memo[p]; !ok {
+			if err := push(p); err != nil {
+				return false, err
+			}
+		}
+	}
+	return c.memo[tip], nil
+}
+
+// containingRefs returns the names of the branches and tags containing the
+// commit. Branches comprise local and remote-tracking branches, e.g. the
+// branches of a mirror.
+func containingRefs(r *git.Repository, c plumbing.Hash) (branches, tags []string, err error) {
+	log.Debug().Stringer("hash", c).Msg("Resolving branches and tags containing commit")
+	cont, err := newContainment(r, c)
+	if err != nil {
+		return nil, nil, err
+	}
+	refs, err := r.References()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer refs.Close()
+
+	bs, ts := make(map[string]bool), make(map[string]bool)
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		n := ref.Name()
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		} else if n.IsBranch() || (n.IsRemote() && strings.HasPrefix(n.String(), "refs/remotes/origin/")) {
+			ok, err := cont.contains(ref.Hash())
+			if ok {
+				bs[strings.TrimPrefix(n.Short(), "origin/")] = true
+			}
+			return err
+		} else if n.IsTag() {
+			h, ok := peel(r, ref.Hash())
+			if !ok {
+				return nil
+			}
+			ok, err := cont.contains(h)
+			if ok {
+				ts[n.Short()] = true
+			}
+			return err
+		}
+		return nil
+	})
+	return sortedSet(bs), sortedSet(ts), err
+}
+
+// peel returns the commit of a lightweight or annotated tag.
+func peel(r *git.Repository, h plumbing.Hash) (plumbing.Hash, bool) {
+	t, err := r.TagObject(h)
+	if err != nil {
+		_, err = r.CommitObject(h)
+		return h, err == nil
+	}
+	if t.TargetType != plumbing.CommitObject {
+		return h, false
+	}
+	c, err := t.Commit()
+	if err != nil {
+		return h, false
+	}
+	return c.Hash, true
+}
+
+func sortedSet(m map[string]bool) []string {
+	s := make([]string, 0, len(m))
+	for k := range m {
+		if k != "HEAD" {
+			s = append(s, k)
+		}
+	}
+	sort.Strings(s)
+	return s
+}
@@ -0,0 +1,144 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	fgraph "github.com/go-git/go-git/v5/plumbing/format/commitgraph"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/object/commitgraph"
+)
+
+// commitGraph returns a commit-graph of all commits of g, which contains
+// their generation numbers.
+func (g *graph) commitGraph() *fgraph.MemoryIndex {
+	g.t.Helper()
+	gens := make(map[plumbing.Hash]int)
+	var gen func(h plumbing.Hash) int
+	gen = func(h plumbing.Hash) int {
+		if n, ok := gens[h]; ok {
+			return n
+		}
+		c, err := g.r.CommitObject(h)
+		if err != nil {
+			g.t.Fatal(err)
+		}
+		n := 1
+		for _, p := range c.ParentHashes {
+			if pg := gen(p) + 1; pg > n {
+				n = pg
+			}
+		}
+		gens[h] = n
+		return n
+	}
+
+	idx := fgraph.NewMemoryIndex()
+	for _, h := range g.hashes {
+		c, _ := g.r.CommitObject(h)
+		idx.Add(h, &fgraph.CommitData{TreeHash: c.TreeHash, ParentHashes: c.ParentHashes, Generation: gen(h), When: c.Committer.When})
+	}
+	return idx
+}
+
+// recordingIndex records the commits, whose nodes were requested.
+type recordingIndex struct {
+	commitgraph.CommitNodeIndex
+	visited map[plumbing.Hash]bool
+}
+
+func (i *recordingIndex) Get(h plumbing.Hash) (commitgraph.CommitNode, error) {
+	i.visited[h] = true
+	return i.CommitNodeIndex.Get(h)
+}
+
+func TestContainment(t *testing.T) {
+	// A - B - C - D
+	//      \
+	//       E - F
+	g := newGraph(t)
+	g.commit("A")
+	g.commit("B", "A")
+	g.commit("C", "B")
+	g.commit("D", "C")
+	g.commit("E", "B")
+	g.commit("F", "E")
+
+	tests := []struct {
+		name        string
+		commitGraph bool
+	}{
+		{"commit-graph", true},
+		{"objects", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := &recordingIndex{commitgraph.NewObjectCommitNodeIndex(g.r.Storer), make(map[plumbing.Hash]bool)}
+			if tt.commitGraph {
+				idx.CommitNodeIndex = commitgraph.NewGraphCommitNodeIndex(g.commitGraph(), g.r.Storer)
+			}
+			target, err := idx.Get(g.hashes["C"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &containment{idx, target, map[plumbing.Hash]bool{g.hashes["C"]: true}}
+
+			for tip, want := range map[string]bool{"D": true, "C": true, "F": false, "E": false} {
+				if got, err := c.contains(g.hashes[tip]); err != nil || got != want {
+					t.Errorf("contains(%s) = %v, %v, want %v", tip, got, err, want)
+				}
+			}
+			// with generation numbers, the walk of F stops at B, which is older than C
+			if visited := idx.visited[g.hashes["A"]]; visited == tt.commitGraph {
+				t.Errorf("A visited = %v, want %v", visited, !tt.commitGraph)
+			}
+		})
+	}
+}
+
+func TestContainingRefs(t *testing.T) {
+	g := newGraph(t)
+	g.commit("A")
+	g.commit("B", "A")
+	g.commit("C", "B")
+	g.commit("F", "B")
+	g.branch("main", "C")
+	g.branch("feature", "F")
+	for name, msg := range map[string]string{"refs/remotes/origin/release/1.x": "C", "refs/remotes/upstream/next": "C", "refs/tags/v1": "C", "refs/tags/v0": "A"} {
+		ref := plumbing.NewHashReference(plumbing.ReferenceName(name), g.hashes[msg])
+		if err := g.r.Storer.SetReference(ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sig := &object.Signature{Name: "Jane", Email: "jane@acme.org"}
+	if _, err := g.r.CreateTag("v1-rc", g.hashes["B"], &git.CreateTagOptions{Tagger: sig, Message: "rc"}); err != nil {
+		t.Fatal(err)
+	}
+
+	bs, ts, err := containingRefs(g.r, g.hashes["B"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"feature", "main", "release/1.x"}; !reflect.DeepEqual(bs, want) {
+		t.Errorf("branches = %v, want %v", bs, want)
+	}
+	if want := []string{"v1", "v1-rc"}; !reflect.DeepEqual(ts, want) {
+		t.Errorf("tags = %v, want %v", ts, want)
+	}
+}
@@ -0,0 +1,134 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/gschauer/heimdall-dev/res"
+	"github.com/rs/zerolog/log"
+)
+
+// mirrorRefSpecs fetches all branches as remote-tracking branches and all tags.
+var mirrorRefSpecs = []config.RefSpec{
+	"+refs/heads/*:refs/remotes/origin/*",
+	"+refs/tags/*:refs/tags/*",
+}
+
+// mirrorDir returns the directory of the bare mirror of the URL within the
+// cache directory, e.g. "zzz-web-1a2b3c4d5e6f7a8b.git".
+func mirrorDir(cache, url string) string {
+	h := sha256.Sum256([]byte(url))
+	n, _ := res.CompRev(url)
+	return filepath.Join(cache, n+"-"+hex.EncodeToString(h[:8])+".git")
+}
+
+// mirrorLocks contains the unlock functions of the mirrors opened by this run.
+var mirrorLocks []func()
+
+// openMirror opens the bare mirror of the URL in the cache directory and
+// fetches incrementally, pruning deleted branches. If the mirror does not
+// exist yet, it is created. The mirror stays locked until closeRepos, so that
+// concurrent runs sharing the cache neither fetch while it is read nor read
+// while it is fetched.
+func openMirror(cache, url string, a transport.AuthMethod) (*git.Repository, error) {
+	dir := mirrorDir(cache, url)
+	if err := os.MkdirAll(cache, 0700); err != nil {
+		return nil, err
+	}
+
+	unlock, err := lock(dir + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	r, err := fetchMirror(dir, url, a)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	mirrorLocks = append(mirrorLocks, unlock)
+	return r, nil
+}
+
+func fetchMirror(dir, url string, a transport.AuthMethod) (*git.Repository, error) {
+	r, err := git.PlainOpen(dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		log.Info().Str("URL", url).Str("dir", dir).Msg("Creating Git mirror")
+		if r, err = git.PlainInit(dir, true); err != nil {
+			return nil, err
+		}
+		_, err = r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{url}, Fetch: mirrorRefSpecs})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("URL", url).Str("dir", dir).Msg("Fetching Git mirror")
+	err = r.Fetch(&git.FetchOptions{RefSpecs: mirrorRefSpecs, Auth: a, Tags: git.AllTags, Force: true})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, err
+	}
+	return r, prune(r, a)
+}
+
+// prune removes the remote-tracking branches of branches deleted on the
+// remote, like "git fetch --prune". Tags are kept, since release tags may only
+// exist in the mirror.
+func prune(r *git.Repository, a transport.AuthMethod) error {
+	rm, err := r.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return err
+	}
+	remote, err := rm.List(&git.ListOptions{Auth: a})
+	if err != nil {
+		return err
+	}
+	heads := make(map[plumbing.ReferenceName]bool)
+	for _, ref := range remote {
+		if ref.Name().IsBranch() {
+			heads[plumbing.NewRemoteReferenceName(git.DefaultRemoteName, ref.Name().Short())] = true
+		}
+	}
+
+	refs, err := r.References()
+	if err != nil {
+		return err
+	}
+	var stale []plumbing.ReferenceName
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name().IsRemote() && !heads[ref.Name()] {
+			stale = append(stale, ref.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, n := range stale {
+		log.Debug().Str("ref", n.String()).Msg("Pruning deleted branch")
+		if err = r.Storer.RemoveReference(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -15,200 +15,288 @@
 package git
 
 import (
-	"os"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
-	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/gschauer/heimdall-dev/cfg"
 	"github.com/gschauer/heimdall-dev/internal"
 	"github.com/gschauer/heimdall-dev/plugin"
 	"github.com/gschauer/heimdall-dev/release"
+	"github.com/gschauer/heimdall-dev/res"
 	"github.com/rs/zerolog/log"
 )
 
-var user, pass string
-
 type CommitPlugin struct {
-	branch  string
-	commits []*object.Commit
+	components map[string]*Component
+}
+
+// Component contains the Git facts of a component of the release.
+type Component struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Path of the component within the repository, if any.
+	Path   string `json:"path,omitempty"`
+	OldRev string `json:"oldRev"`
+	NewRev string `json:"newRev"`
+	// Hash is the commit of NewRev, i.e. the evaluated commit.
+	Hash               string   `json:"hash"`
+	Branch             string   `json:"branch"`
+	ContainingBranches []string `json:"containingBranches"`
+	ContainingTags     []string `json:"containingTags"`
+	// Relation of OldRev and NewRev, e.g. FastForward, and their merge bases
+	Relation   string   `json:"relation"`
+	MergeBases []string `json:"mergeBases"`
+	Commits    []Commit `json:"commits"`
+	// Changes are the files changed between OldRev and NewRev.
+	Changes    []FileChange `json:"changes"`
+	DiffStat   DiffStat     `json:"diffStat"`
+	Submodules []Submodule  `json:"submodules"`
+	// TagSignature is only present, if NewRev is an annotated tag.
+	TagSignature *Verification `json:"tagSignature,omitempty"`
+
+	repo *git.Repository
+}
+
+type Commit struct {
+	Hash      string    `json:"hash"`
+	Author    Signature `json:"author"`
+	Committer Signature `json:"committer"`
+	Message   string    `json:"message"`
+	Parents   []string  `json:"parents"`
+
+	// structured message, see parseMessage
+	Type     string              `json:"type"`
+	Scope    string              `json:"scope"`
+	Breaking bool                `json:"breaking"`
+	Subject  string              `json:"subject"`
+	Body     string              `json:"body"`
+	Footers  map[string][]string `json:"footers"`
+	JiraKeys []string            `json:"jiraKeys"`
+	Merge    bool                `json:"merge"`
+	Revert   bool                `json:"revert"`
+	Reverts  string              `json:"reverts,omitempty"`
+
+	// DCO is true, if the author signed off the commit, see checkIdentity.
+	DCO              bool `json:"dco"`
+	CommitterDiffers bool `json:"committerDiffers"`
+	// Allowed is true, if the author is listed in the CLA file or has an allowed domain.
+	Allowed bool `json:"allowed"`
+
+	Verification
 }
 
+type Signature struct {
+	Name   string    `json:"name"`
+	Email  string    `json:"email"`
+	Domain string    `json:"domain"`
+	When   time.Time `json:"when"`
+}
+
+const componentsFile = "components.json"
+
 func init() {
 	if !cfg.IsGitEnabled() {
+		plugin.RegisterOffline(&CommitPlugin{})
 		return
 	}
-
-	if user = os.Getenv("GIT_USERNAME"); user == "" {
-		log.Warn().Str("name", "GIT_USERNAME").Msg("undefined environment variable")
-		return
-	}
-	if pass = os.Getenv("GIT_PASSWORD"); pass == "" {
-		log.Warn().Str("name", "GIT_PASSWORD").Msg("undefined environment variable")
-		return
-	}
-
 	plugin.Register(&CommitPlugin{})
 }
 
 func (p *CommitPlugin) Load(o, n release.Info) {
+	defer closeRepos()
 	old := map[string]string{}
 	for _, c := range o.Components {
-		url, rev, _ := strings.Cut(c, "@")
+		url, rev := res.SplitRev(c)
 		old[url] = rev
 	}
 
+	v := internal.Must(newVerifier())
+	allow := internal.Must(loadAllowlist())
+	p.components = make(map[string]*Component)
 	for _, c := range n.Components {
-		url, newRev, _ := strings.Cut(c, "@")
-		if oldRev, ok := old[url]; ok && oldRev != newRev {
-			r := internal.Must(open(url, user, pass))
-			base := mergeBase(r, oldRev, newRev)
-			p.commits = append(p.commits, p.loadCommits(r, base)...)
-			findBranches(r, resolve(r, newRev)) // TODO: implement correct remote resolution without clone
-			p.branch = newRev
+		url, newRev := res.SplitRev(c)
+		name, _ := res.CompRev(c)
+		repoURL, dir := res.SplitPath(url)
+		// facts of all plugins refer to components by name, so names must be unique
+		internal.MustOkMsgf(name, p.components[name] == nil, "duplicate component name %s: %s", name, url)
+		comp := &Component{Name: name, URL: repoURL, Path: dir, OldRev: old[url], NewRev: newRev, Branch: newRev}
+		p.components[name] = comp
+
+		comp.repo = internal.Must(open(repoURL))
+		head := resolve(comp.repo, newRev)
+		comp.Hash = head.String()
+		comp.ContainingBranches, comp.ContainingTags = internal.Must2(containingRefs(comp.repo, head))
+		if ref, err := comp.repo.Tag(newRev); err == nil {
+			if t, err := comp.repo.TagObject(ref.Hash()); err == nil {
+				ts := v.tag(comp.repo, t)
+				comp.TagSignature = &ts
+			}
+		}
+
+		if comp.OldRev == "" {
+			continue
+		} else if comp.OldRev == newRev {
+			comp.Relation = FastForward
+			continue
+		}
+		rel, bases := internal.Must2(relation(comp.repo, comp.OldRev, head))
+		comp.Relation = rel
+		for _, b := range bases {
+			comp.MergeBases = append(comp.MergeBases, b.String())
+		}
+		// commits of unrelated histories are omitted, since they would include the whole history
+		if len(bases) > 0 {
+			comp.Commits = internal.Must(scopeCommits(comp.repo, loadCommits(comp.repo, head, bases, v), dir))
+		}
+		if old, ok := resolveRev(comp.repo, comp.OldRev); ok {
+			comp.Changes, comp.DiffStat = internal.Must2(diffTrees(comp.repo, old, head, dir))
+			comp.Submodules = internal.Must(loadSubmodules(comp.repo, repoURL, head, comp.Changes, v))
+		}
+		for i := range comp.Commits {
+			comp.Commits[i].checkIdentity(allow)
+		}
+		for _, sm := range comp.Submodules {
+			for i := range sm.Commits {
+				sm.Commits[i].checkIdentity(allow)
+			}
 		}
 	}
 }
+
 func (p *CommitPlugin) InitEnv(env map[string]any) {
+	cs := make(map[string]any, len(p.components))
+	var commits, changes []any
+	var fcs []FileChange
+	var stat DiffStat
+	var branches, tags, rels []string
+	sigs := map[string]int{"commits": 0, "signed": 0, "valid": 0}
+	for n, c := range p.components {
+		all := c.Commits
+		if c.Relation != "" {
+			rels = append(rels, c.Relation)
+		}
+		for _, sm := range c.Submodules {
+			all = append(all[:len(all):len(all)], sm.Commits...)
+			rels = append(rels, sm.Relation)
+		}
+		for _, co := range all {
+			commits = append(commits, res.ToMap(co))
+			sigs["commits"]++
+			if co.Signed {
+				sigs["signed"]++
+			}
+			if co.SignatureValid {
+				sigs["valid"]++
+			}
+		}
+		m := res.ToMap(c)
+		cs[n] = m
+		if l, ok := m["changes"].([]any); ok {
+			changes = append(changes, l...)
+		}
+		fcs = append(fcs, c.Changes...)
+		stat.Files += c.DiffStat.Files
+		stat.Additions += c.DiffStat.Additions
+		stat.Deletions += c.DiffStat.Deletions
+		if len(cs) == 1 {
+			branches, tags = c.ContainingBranches, c.ContainingTags
+		} else {
+			branches, tags = intersect(branches, c.ContainingBranches), intersect(tags, c.ContainingTags)
+		}
+	}
+
 	env["git"] = map[string]any{
-		"branch":         p.branch,
-		"commits":        p.commits,
+		"components": cs,
+		// commits of all components including the commits of their submodules
+		"commits": commits,
+		// branches and tags, which contain the evaluated commit of every component
+		"containingBranches": branches,
+		"containingTags":     tags,
+		// relations of the old and new revision of all components and submodules, see relation
+		"relations": rels,
+		// number of commits, signed commits and commits with a valid signature
+		"signatures": sigs,
+		// changed files of all components and their totals
+		"changes":        changes,
+		"diffStat":       res.ToMap(stat),
+		"changed":        changed(fcs),
 		"validCommitMsg": validCommitMsg,
 	}
 }
 
-func validCommitMsg(c *object.Commit) bool {
-	return strings.HasPrefix(c.Message, cfg.GetProjectKey()+"-")
+// Components returns the Git facts of all components, e.g. for plugins, which
+// enrich the commits of the release.
+func (p *CommitPlugin) Components() map[string]*Component {
+	return p.components
 }
 
-func open(uri, user, pass string) (*git.Repository, error) {
-	if strings.HasPrefix(uri, "https://") {
-		log.Info().Str("URL", uri).Msg("Cloning Git repo")
-		return git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
-			URL:  uri,
-			Auth: &http.BasicAuth{Username: user, Password: pass},
-		})
-	}
-
-	log.Info().Str("dir", uri).Msg("Opening Git repo")
-	return git.PlainOpen(uri)
+func (p *CommitPlugin) Save(dir string) error {
+	return plugin.SaveJSON(dir, componentsFile, p.components)
 }
 
-func mergeBase(r *git.Repository, revs ...string) *object.Commit {
-	hashes := make([]plumbing.Hash, len(revs))
-	for _, rev := range revs {
-		hashes = append(hashes, resolve(r, rev))
-	}
-
-	commits := make([]*object.Commit, len(hashes))
-	for _, hash := range hashes {
-		commits = append(commits, internal.Must(r.CommitObject(hash)))
-	}
-
-	if len(commits) != 2 {
-		internal.MustOkMsgf[any](nil, false, "expected 2 commits, got %d", len(commits))
-	}
-	bs := internal.Must(commits[0].MergeBase(commits[1]))
-	if len(bs) != 1 {
-		internal.MustOkMsgf[any](nil, false, "expected 1 common ancestor, got %d", len(bs))
-	}
-	log.Debug().Stringer("old", commits[0].Hash).Stringer("new", commits[1].Hash).
-		Stringer("hash", bs[0].Hash).Msg("Resolved Git merge base")
-	return bs[0]
+func (p *CommitPlugin) Restore(dir string) error {
+	return plugin.LoadJSON(dir, componentsFile, &p.components)
 }
 
-func resolve(r *git.Repository, rev string) plumbing.Hash {
-	if hash, err := r.ResolveRevision(plumbing.Revision("refs/heads/" + rev)); err == nil {
-		return *hash
-	} else if hash, err = r.ResolveRevision(plumbing.Revision("refs/tags/" + rev)); err == nil {
-		return *hash
-	} else if hash, err = r.ResolveRevision(plumbing.Revision("refs/remotes/origin/" + rev)); err == nil {
-		return *hash
-	} else {
-		internal.MustOkMsgf[any](nil, false, "cannot resolve revision %s", rev)
-	}
-	return plumbing.ZeroHash
+// validCommitMsg checks whether the commit message starts with the project key.
+func validCommitMsg(c map[string]any) bool {
+	msg, _ := c["message"].(string)
+	return strings.HasPrefix(msg, cfg.GetProjectKey()+"-")
 }
 
-func (p *CommitPlugin) loadCommits(r *git.Repository, base *object.Commit) (cs []*object.Commit) {
-	log.Debug().Str("repository", internal.Must(r.Remote("origin")).Config().URLs[0]).Msg("Loading commits")
-	l := internal.Must(r.Log(&git.LogOptions{From: base.Hash}))
-	defer l.Close()
-	internal.MustNoErr(l.ForEach(func(c *object.Commit) error {
-		cs = append(cs, c)
-		return nil
-	}))
-	return
-}
-
-func findBranches(r *git.Repository, c plumbing.Hash) (bs []plumbing.ReferenceName) {
-	log.Debug().Stringer("hash", c).Msg("Resolve branch(es) for commit")
-	visited := make(map[plumbing.Hash]bool)
-	refs := listRemoteRefs(r)
-
-	for _, ref := range refs {
-		n := ref.Name()
-		if n.IsBranch() {
-			internal.MustNoErr(r.Fetch(&git.FetchOptions{
-				RefSpecs: []config.RefSpec{"refs/*:refs/*", "HEAD:refs/heads/HEAD"},
-				Auth:     &http.BasicAuth{Username: user, Password: pass},
-			}))
-
-			b := internal.Must(r.Reference(n, true))
-			ok := internal.Must(reaches(r, b.Hash(), c, visited))
-			if ok {
-				bs = append(bs, n)
+func intersect(a, b []string) (c []string) {
+	for _, s := range a {
+		for _, t := range b {
+			if s == t {
+				c = append(c, s)
+				break
 			}
 		}
 	}
 	return
 }
 
-func listRemoteRefs(r *git.Repository) (bs []*plumbing.Reference) {
-	rem := internal.Must(r.Remote("origin"))
-	refs := internal.Must(rem.List(&git.ListOptions{
-		Auth: &http.BasicAuth{Username: user, Password: pass},
-	}))
-
-	refPrefix := "refs/heads/"
-	for _, ref := range refs {
-		if !strings.HasPrefix(ref.Name().String(), refPrefix) {
-			continue
-		}
-		bs = append(bs, ref)
-	}
-	return
-}
-
-func reaches(r *git.Repository, start, c plumbing.Hash, visited map[plumbing.Hash]bool) (bool, error) {
-	if v, ok := visited[start]; ok {
-		return v, nil
+func newCommit(c *object.Commit, v *verifier) Commit {
+	ps := make([]string, len(c.ParentHashes))
+	for i, h := range c.ParentHashes {
+		ps[i] = h.String()
 	}
-	if start == c {
-		visited[start] = true
-		return true, nil
-	}
-
-	co, err := r.CommitObject(start)
-	if err != nil {
-		return false, err
+	co := Commit{
+		Hash:      c.Hash.String(),
+		Author:    newSignature(c.Author),
+		Committer: newSignature(c.Committer),
+		Message:   c.Message,
+		Parents:   ps,
 	}
+	co.parseMessage()
+	co.Verification = v.commit(c)
+	return co
+}
 
-	for _, p := range co.ParentHashes {
-		v, err := reaches(r, p, c, visited)
-		if err != nil {
-			return false, err
-		}
-		if v {
-			visited[start] = true
-			return true, nil
-		}
+// loadCommits returns the commits reachable from head, but not from any base,
+// i.e. the range bases..head.
+func loadCommits(r *git.Repository, head plumbing.Hash, bases []plumbing.Hash, v *verifier) (cs []Commit) {
+	log.Debug().Stringer("head", head).Int("bases", len(bases)).Msg("Loading commits")
+	// ignoring the bases alone is not enough, since side branches, which forked
+	// before a base and were merged after it, lead to the ancestors of the base
+	seen := make(map[plumbing.Hash]bool)
+	for _, b := range bases {
+		it := object.NewCommitPreorderIter(internal.Must(r.CommitObject(b)), seen, nil)
+		internal.MustNoErr(it.ForEach(func(c *object.Commit) error {
+			seen[c.Hash] = true
+			return nil
+		}))
+		it.Close()
 	}
 
-	visited[start] = false
-	return false, nil
+	it := object.NewCommitPreorderIter(internal.Must(r.CommitObject(head)), seen, nil)
+	defer it.Close()
+	internal.MustNoErr(it.ForEach(func(c *object.Commit) error {
+		cs = append(cs, newCommit(c, v))
+		return nil
+	}))
+	return
 }
@@ -0,0 +1,141 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// graph builds a commit graph in memory. Commits have an empty tree and are
+// one minute apart, so that their order is deterministic.
+type graph struct {
+	t      *testing.T
+	r      *git.Repository
+	tree   plumbing.Hash
+	when   time.Time
+	hashes map[string]plumbing.Hash
+}
+
+func newGraph(t *testing.T) *graph {
+	t.Helper()
+	r, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return graphOf(t, r)
+}
+
+// graphOf builds the commit graph in the repository.
+func graphOf(t *testing.T, r *git.Repository) *graph {
+	t.Helper()
+	o := r.Storer.NewEncodedObject()
+	if err := (&object.Tree{}).Encode(o); err != nil {
+		t.Fatal(err)
+	}
+	tree, err := r.Storer.SetEncodedObject(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &graph{t, r, tree, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), make(map[string]plumbing.Hash)}
+}
+
+// commit creates a commit with the message and the given parents.
+func (g *graph) commit(msg string, parents ...string) plumbing.Hash {
+	g.t.Helper()
+	g.when = g.when.Add(time.Minute)
+	sig := object.Signature{Name: "Jane", Email: "jane@acme.org", When: g.when}
+	c := &object.Commit{Author: sig, Committer: sig, Message: msg, TreeHash: g.tree}
+	for _, p := range parents {
+		c.ParentHashes = append(c.ParentHashes, g.hashes[p])
+	}
+	o := g.r.Storer.NewEncodedObject()
+	if err := c.Encode(o); err != nil {
+		g.t.Fatal(err)
+	}
+	h, err := g.r.Storer.SetEncodedObject(o)
+	if err != nil {
+		g.t.Fatal(err)
+	}
+	g.hashes[msg] = h
+	return h
+}
+
+// branch points the branch to the commit with the message.
+func (g *graph) branch(name, msg string) {
+	g.t.Helper()
+	ref := plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), g.hashes[msg])
+	if err := g.r.Storer.SetReference(ref); err != nil {
+		g.t.Fatal(err)
+	}
+}
+
+// messages returns the sorted messages of the commits.
+func messages(cs []Commit) []string {
+	ms := make([]string, 0, len(cs))
+	for _, c := range cs {
+		ms = append(ms, c.Message)
+	}
+	sort.Strings(ms)
+	return ms
+}
+
+func TestLoadCommits(t *testing.T) {
+	// A - B - C ------- M1 ------- M2
+	//  \                /          /
+	//   S1 - S2 -------   D1 - D2     (S1 forked before and merged after B)
+	g := newGraph(t)
+	g.commit("A")
+	g.commit("B", "A")
+	g.commit("C", "B")
+	g.commit("S1", "A")
+	g.commit("S2", "S1")
+	g.commit("M1", "C", "S2")
+	g.commit("D1", "B")
+	g.commit("D2", "D1")
+	g.commit("M2", "M1", "D2")
+
+	tests := []struct {
+		name  string
+		head  string
+		bases []string
+		want  []string
+	}{
+		{"linear", "C", []string{"B"}, []string{"C"}},
+		{"side branch forked before base", "M1", []string{"B"}, []string{"C", "M1", "S1", "S2"}},
+		{"side branch merged before base", "M2", []string{"M1"}, []string{"D1", "D2", "M2"}},
+		{"several bases", "M2", []string{"C", "S2"}, []string{"D1", "D2", "M1", "M2"}},
+		{"base is head", "M2", []string{"M2"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bases []plumbing.Hash
+			for _, b := range tt.bases {
+				bases = append(bases, g.hashes[b])
+			}
+			got := messages(loadCommits(g.r, g.hashes[tt.head], bases, &verifier{}))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadCommits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
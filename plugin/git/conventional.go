@@ -0,0 +1,103 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gschauer/heimdall-dev/cfg"
+)
+
+var (
+	// headerRe matches the header of a conventional commit, e.g. "feat(api)!: add endpoint".
+	headerRe = regexp.MustCompile(`^(\w+)(?:\(([^()]*)\))?(!)?: (.*)$`)
+	// footerRe matches a footer, e.g. "Signed-off-by: Jane <jane@acme.org>" or "Refs #123".
+	footerRe  = regexp.MustCompile(`^([\w-]+|BREAKING CHANGE)(?:: | #)(.*)$`)
+	revertsRe = regexp.MustCompile(`This reverts commit ([0-9a-f]{7,40})`)
+)
+
+// parseMessage parses the message according to the Conventional Commits
+// specification. Messages, which do not follow the specification, only have
+// a subject, a body and footers.
+func (c *Commit) parseMessage() {
+	msg := strings.TrimSpace(strings.ReplaceAll(c.Message, "\r\n", "\n"))
+	header, rest, _ := strings.Cut(msg, "\n")
+	c.Subject = header
+	if m := headerRe.FindStringSubmatch(header); m != nil {
+		c.Type, c.Scope, c.Breaking, c.Subject = strings.ToLower(m[1]), m[2], m[3] == "!", m[4]
+	}
+
+	paras := strings.Split(strings.TrimSpace(rest), "\n\n")
+	c.Footers = make(map[string][]string)
+	if len(paras) > 0 && parseFooters(paras[len(paras)-1], c.Footers) {
+		paras = paras[:len(paras)-1]
+	}
+	c.Body = strings.TrimSpace(strings.Join(paras, "\n\n"))
+	if _, ok := c.Footers["BREAKING CHANGE"]; ok {
+		c.Breaking = true
+	} else if _, ok = c.Footers["BREAKING-CHANGE"]; ok {
+		c.Breaking = true
+	}
+
+	c.JiraKeys = jiraKeys(c.Message)
+	c.Merge = len(c.Parents) > 1
+	c.Revert = c.Type == "revert" || strings.HasPrefix(header, `Revert "`)
+	if m := revertsRe.FindStringSubmatch(c.Message); m != nil {
+		c.Revert, c.Reverts = true, m[1]
+	}
+}
+
+// parseFooters parses the paragraph into footers. It returns false, if the
+// paragraph does not start with a footer. Lines, which are not footers,
+// continue the value of the previous footer.
+func parseFooters(para string, fs map[string][]string) bool {
+	lines := strings.Split(para, "\n")
+	if !footerRe.MatchString(lines[0]) {
+		return false
+	}
+
+	var key string
+	for _, l := range lines {
+		if m := footerRe.FindStringSubmatch(l); m != nil {
+			key = m[1]
+			fs[key] = append(fs[key], m[2])
+		} else {
+			vs := fs[key]
+			vs[len(vs)-1] += "\n" + l
+		}
+	}
+	return true
+}
+
+// jiraKeys returns the distinct keys of Jira issues of the configured
+// projects, which are mentioned anywhere in the message.
+func jiraKeys(msg string) []string {
+	ks := []string{}
+	ps := make([]string, len(cfg.GetProjectKeys()))
+	for i, k := range cfg.GetProjectKeys() {
+		ps[i] = regexp.QuoteMeta(k)
+	}
+	re := regexp.MustCompile(`\b(?:` + strings.Join(ps, "|") + `)-\d+\b`)
+
+	seen := make(map[string]bool)
+	for _, k := range re.FindAllString(msg, -1) {
+		if !seen[k] {
+			seen[k] = true
+			ks = append(ks, k)
+		}
+	}
+	return ks
+}
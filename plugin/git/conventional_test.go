@@ -0,0 +1,121 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     string
+		parents []string
+		want    Commit
+	}{
+		{
+			name: "conventional",
+			msg:  "feat(api): add endpoint\n\nThe endpoint lists releases.\n\nRefs #12\nSigned-off-by: Jane <jane@acme.org>\n",
+			want: Commit{
+				Type: "feat", Scope: "api", Subject: "add endpoint", Body: "The endpoint lists releases.",
+				Footers:  map[string][]string{"Refs": {"12"}, "Signed-off-by": {"Jane <jane@acme.org>"}},
+				JiraKeys: []string{},
+			},
+		},
+		{
+			name: "breaking header",
+			msg:  "Fix!: drop v1",
+			want: Commit{Type: "fix", Breaking: true, Subject: "drop v1", Footers: map[string][]string{}, JiraKeys: []string{}},
+		},
+		{
+			name: "breaking footer",
+			msg:  "refactor: rename flags\r\n\r\nBREAKING CHANGE: -out replaces -dir\r\nand -store replaces -history",
+			want: Commit{
+				Type: "refactor", Breaking: true, Subject: "rename flags",
+				Footers:  map[string][]string{"BREAKING CHANGE": {"-out replaces -dir\nand -store replaces -history"}},
+				JiraKeys: []string{},
+			},
+		},
+		{
+			name: "breaking footer with hyphen",
+			msg:  "chore: bump Go\n\nBREAKING-CHANGE: requires Go 1.19",
+			want: Commit{
+				Type: "chore", Breaking: true, Subject: "bump Go",
+				Footers: map[string][]string{"BREAKING-CHANGE": {"requires Go 1.19"}}, JiraKeys: []string{},
+			},
+		},
+		{
+			name: "free-form with Jira keys",
+			msg:  "ZZZ-12 fix login\n\nSee ZZZ-12 and ZZZ-7, but not XZZZ-1.\n\nNo footer here",
+			want: Commit{
+				Subject: "ZZZ-12 fix login", Body: "See ZZZ-12 and ZZZ-7, but not XZZZ-1.\n\nNo footer here",
+				Footers: map[string][]string{}, JiraKeys: []string{"ZZZ-12", "ZZZ-7"},
+			},
+		},
+		{
+			name:    "merge",
+			msg:     "Merge branch 'feature'",
+			parents: []string{"a", "b"},
+			want:    Commit{Subject: "Merge branch 'feature'", Footers: map[string][]string{}, JiraKeys: []string{}, Merge: true},
+		},
+		{
+			name: "git revert",
+			msg:  "Revert \"feat: add endpoint\"\n\nThis reverts commit 0123456789abcdef0123456789abcdef01234567.",
+			want: Commit{
+				Subject: `Revert "feat: add endpoint"`, Body: "This reverts commit 0123456789abcdef0123456789abcdef01234567.",
+				Footers: map[string][]string{}, JiraKeys: []string{}, Revert: true, Reverts: "0123456789abcdef0123456789abcdef01234567",
+			},
+		},
+		{
+			name: "conventional revert",
+			msg:  "revert: add endpoint",
+			want: Commit{Type: "revert", Subject: "add endpoint", Footers: map[string][]string{}, JiraKeys: []string{}, Revert: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Commit{Message: tt.msg, Parents: tt.parents}
+			c.parseMessage()
+			tt.want.Message, tt.want.Parents = tt.msg, tt.parents
+			if !reflect.DeepEqual(c, tt.want) {
+				t.Errorf("parseMessage() = %+v, want %+v", c, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFooters(t *testing.T) {
+	tests := []struct {
+		name string
+		para string
+		ok   bool
+		want map[string][]string
+	}{
+		{"no footer", "Just a body.\nRefs #1", false, map[string][]string{}},
+		{"repeated", "Co-authored-by: A <a@acme.org>\nCo-authored-by: B <b@acme.org>", true,
+			map[string][]string{"Co-authored-by": {"A <a@acme.org>", "B <b@acme.org>"}}},
+		{"issue reference", "Fixes #42", true, map[string][]string{"Fixes": {"42"}}},
+		{"continuation", "Reviewed-by: A\n  and B\nRefs #3", true, map[string][]string{"Reviewed-by": {"A\n  and B"}, "Refs": {"3"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := make(map[string][]string)
+			if ok := parseFooters(tt.para, fs); ok != tt.ok || !reflect.DeepEqual(fs, tt.want) {
+				t.Errorf("parseFooters() = %v, %v, want %v, %v", ok, fs, tt.ok, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/rs/zerolog/log"
+)
+
+// Types of changes
+const (
+	Added    = "added"
+	Modified = "modified"
+	Deleted  = "deleted"
+	Renamed  = "renamed"
+)
+
+// FileChange describes a file, which differs between the old and the new
+// revision of a component.
+type FileChange struct {
+	Path string `json:"path"`
+	// OldPath is the path in the old revision, if the file was renamed.
+	OldPath   string `json:"oldPath,omitempty"`
+	Change    string `json:"change"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary"`
+	// Submodule is true, if the submodule pointer changed, see loadSubmodules.
+	Submodule bool `json:"submodule"`
+
+	oldHash, newHash plumbing.Hash
+}
+
+// DiffStat contains the totals of all file changes.
+type DiffStat struct {
+	Files     int `json:"files"`
+	Additions int `json:"additions"`
+	Deletions int `json:"deletions"`
+}
+
+// diffTrees compares the trees of the old and the new commit with rename
+// detection. If dir is not empty, only files within dir are considered.
+func diffTrees(r *git.Repository, old, head plumbing.Hash, dir string) ([]FileChange, DiffStat, error) {
+	log.Debug().Stringer("old", old).Stringer("head", head).Msg("Computing Git diff")
+	var stat DiffStat
+	a, err := tree(r, old)
+	if err != nil {
+		return nil, stat, err
+	}
+	b, err := tree(r, head)
+	if err != nil {
+		return nil, stat, err
+	}
+	chs, err := object.DiffTreeWithOptions(context.Background(), a, b, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return nil, stat, err
+	}
+
+	fcs := make([]FileChange, 0, len(chs))
+	for _, ch := range chs {
+		if !inDir(ch.From.Name, dir) && !inDir(ch.To.Name, dir) {
+			continue
+		}
+		fc, err := newFileChange(ch)
+		if err != nil {
+			return nil, stat, err
+		}
+		fcs = append(fcs, fc)
+		stat.Files++
+		stat.Additions += fc.Additions
+		stat.Deletions += fc.Deletions
+	}
+	return fcs, stat, nil
+}
+
+func tree(r *git.Repository, h plumbing.Hash) (*object.Tree, error) {
+	c, err := r.CommitObject(h)
+	if err != nil {
+		return nil, err
+	}
+	return c.Tree()
+}
+
+func newFileChange(ch *object.Change) (FileChange, error) {
+	fc := FileChange{Path: ch.To.Name}
+	a, err := ch.Action()
+	if err != nil {
+		return fc, err
+	}
+	switch {
+	case a == merkletrie.Insert:
+		fc.Change = Added
+	case a == merkletrie.Delete:
+		fc.Path, fc.Change = ch.From.Name, Deleted
+	case ch.From.Name != ch.To.Name:
+		fc.OldPath, fc.Change = ch.From.Name, Renamed
+	default:
+		fc.Change = Modified
+	}
+
+	if ch.From.TreeEntry.Mode == filemode.Submodule || ch.To.TreeEntry.Mode == filemode.Submodule {
+		fc.Submodule, fc.oldHash, fc.newHash = true, ch.From.TreeEntry.Hash, ch.To.TreeEntry.Hash
+		return fc, nil
+	}
+
+	p, err := ch.Patch()
+	if err != nil {
+		return fc, err
+	}
+	for _, fp := range p.FilePatches() {
+		fc.Binary = fc.Binary || fp.IsBinary()
+		for _, c := range fp.Chunks() {
+			switch c.Type() {
+			case diff.Add:
+				fc.Additions += countLines(c.Content())
+			case diff.Delete:
+				fc.Deletions += countLines(c.Content())
+			}
+		}
+	}
+	return fc, nil
+}
+
+// inDir checks whether the path is dir or within dir.
+func inDir(path, dir string) bool {
+	return path != "" && (dir == "" || path == dir || strings.HasPrefix(path, dir+"/"))
+}
+
+// scopeCommits returns the commits, which changed dir compared to all of
+// their parents like "git log -- dir".
+func scopeCommits(r *git.Repository, cs []Commit, dir string) ([]Commit, error) {
+	if dir == "" {
+		return cs, nil
+	}
+	var scoped []Commit
+	for _, c := range cs {
+		h, err := dirHash(r, plumbing.NewHash(c.Hash), dir)
+		if err != nil {
+			return nil, err
+		}
+		changed := len(c.Parents) > 0 || !h.IsZero()
+		for _, p := range c.Parents {
+			ph, err := dirHash(r, plumbing.NewHash(p), dir)
+			if err != nil {
+				return nil, err
+			}
+			if ph == h {
+				changed = false
+				break
+			}
+		}
+		if changed {
+			scoped = append(scoped, c)
+		}
+	}
+	return scoped, nil
+}
+
+// dirHash returns the hash of the tree entry dir in the commit or the zero
+// hash, if the commit does not contain dir.
+func dirHash(r *git.Repository, h plumbing.Hash, dir string) (plumbing.Hash, error) {
+	t, err := tree(r, h)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	e, err := t.FindEntry(dir)
+	if errors.Is(err, object.ErrDirectoryNotFound) || errors.Is(err, object.ErrEntryNotFound) {
+		return plumbing.ZeroHash, nil
+	} else if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return e.Hash, nil
+}
+
+func countLines(s string) int {
+	n := strings.Count(s, "\n")
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		n++
+	}
+	return n
+}
+
+// changed returns a function for policies, which checks whether any changed
+// file matches the glob pattern, e.g. "db/migrations/**" or "**/*.sql", see
+// internal.GlobExpr.
+func changed(fcs []FileChange) func(string) bool {
+	return func(pattern string) bool {
+		re := internal.GlobRegexp(pattern)
+		for _, fc := range fcs {
+			if re.MatchString(fc.Path) || (fc.OldPath != "" && re.MatchString(fc.OldPath)) {
+				return true
+			}
+		}
+		return false
+	}
+}
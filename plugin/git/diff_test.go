@@ -0,0 +1,148 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitFiles writes the files into the worktree, removes files with empty
+// content and commits all changes.
+func commitFiles(t *testing.T, r *git.Repository, dir string, files map[string]string) plumbing.Hash {
+	t.Helper()
+	wt, _ := r.Worktree()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if content == "" {
+			if _, err := wt.Remove(name); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sig := &object.Signature{Name: "Jane", Email: "jane@acme.org", When: time.Now()}
+	h, err := wt.Commit("commit", &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestDiffTrees(t *testing.T) {
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := strings.Repeat("Release notes of ZZZ.\n", 10)
+	old := commitFiles(t, r, dir, map[string]string{
+		"a.txt":        "1\n2\n3\n",
+		"docs/old.md":  doc,
+		"bin.dat":      "\x00\x01\x02",
+		"svc/api/x.go": "package api\n",
+	})
+	head := commitFiles(t, r, dir, map[string]string{
+		"a.txt":        "1\nTWO\n3\n4",
+		"docs/old.md":  "",
+		"docs/new.md":  doc,
+		"bin.dat":      "",
+		"svc/api/y.go": "package api\n\nconst Y = 1\n",
+		"svc/db/z.sql": "select 1;\n",
+	})
+
+	tests := []struct {
+		name string
+		dir  string
+		want []FileChange
+		stat DiffStat
+	}{
+		{"all", "", []FileChange{
+			{Path: "a.txt", Change: Modified, Additions: 2, Deletions: 1},
+			{Path: "bin.dat", Change: Deleted, Binary: true},
+			{Path: "docs/new.md", OldPath: "docs/old.md", Change: Renamed},
+			{Path: "svc/api/y.go", Change: Added, Additions: 3},
+			{Path: "svc/db/z.sql", Change: Added, Additions: 1},
+		}, DiffStat{Files: 5, Additions: 6, Deletions: 1}},
+		{"directory", "svc/api", []FileChange{
+			{Path: "svc/api/y.go", Change: Added, Additions: 3},
+		}, DiffStat{Files: 1, Additions: 3}},
+		{"renamed into directory", "docs", []FileChange{
+			{Path: "docs/new.md", OldPath: "docs/old.md", Change: Renamed},
+		}, DiffStat{Files: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fcs, stat, err := diffTrees(r, old, head, tt.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(fcs, tt.want) {
+				t.Errorf("changes = %+v, want %+v", fcs, tt.want)
+			}
+			if stat != tt.stat {
+				t.Errorf("stat = %+v, want %+v", stat, tt.stat)
+			}
+		})
+	}
+}
+
+func TestChanged(t *testing.T) {
+	fcs := []FileChange{
+		{Path: "db/migrations/2.sql", Change: Added},
+		{Path: "docs/new.md", OldPath: "docs/old.md", Change: Renamed},
+	}
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"db/migrations/**", true},
+		{"**/*.sql", true},
+		{"*.sql", false},
+		// renames also match the old path
+		{"docs/old.md", true},
+		{"docs/*.txt", false},
+	}
+	for _, tt := range tests {
+		if got := changed(fcs)(tt.pattern); got != tt.want {
+			t.Errorf("changed(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	for s, want := range map[string]int{"": 0, "a": 1, "a\n": 1, "a\nb": 2, "a\n\n": 2} {
+		if got := countLines(s); got != want {
+			t.Errorf("countLines(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
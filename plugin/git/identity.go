@@ -0,0 +1,81 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gschauer/heimdall-dev/cfg"
+)
+
+// dcoTrailer is the trailer of the Developer Certificate of Origin.
+const dcoTrailer = "Signed-off-by"
+
+// allowlist contains the identities, which are allowed to author commits.
+type allowlist struct {
+	emails  map[string]bool
+	domains map[string]bool
+}
+
+// loadAllowlist reads the email addresses of the CLA file given by
+// cfg.GetCLAFile and adds the domains of cfg.GetAllowedDomains.
+func loadAllowlist() (allowlist, error) {
+	a := allowlist{emails: make(map[string]bool), domains: make(map[string]bool)}
+	for _, d := range cfg.GetAllowedDomains() {
+		a.domains[strings.ToLower(d)] = true
+	}
+
+	f, err := os.Open(cfg.GetCLAFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return a, nil
+	} else if err != nil {
+		return a, err
+	}
+	defer func() { _ = f.Close() }()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if l := strings.TrimSpace(sc.Text()); l != "" && !strings.HasPrefix(l, "#") {
+			a.emails[strings.ToLower(l)] = true
+		}
+	}
+	return a, sc.Err()
+}
+
+func (a allowlist) allows(s Signature) bool {
+	return a.emails[strings.ToLower(s.Email)] || a.domains[s.Domain]
+}
+
+func newSignature(s object.Signature) Signature {
+	_, domain, _ := strings.Cut(s.Email, "@")
+	return Signature{Name: s.Name, Email: s.Email, Domain: strings.ToLower(domain), When: s.When}
+}
+
+// checkIdentity checks the DCO sign-off and whether the author is allowed.
+// A commit complies with the DCO, if it is signed off by its author.
+func (c *Commit) checkIdentity(a allowlist) {
+	for _, v := range c.Footers[dcoTrailer] {
+		if strings.Contains(strings.ToLower(v), "<"+strings.ToLower(c.Author.Email)+">") {
+			c.DCO = true
+			break
+		}
+	}
+	c.CommitterDiffers = !strings.EqualFold(c.Author.Email, c.Committer.Email)
+	c.Allowed = a.allows(c.Author)
+}
@@ -0,0 +1,47 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+//go:build !unix
+
+package git
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// staleLock is the age of a lock file, after which it is considered
+// abandoned by a terminated process.
+const staleLock = 30 * time.Minute
+
+// lock acquires an exclusive lock by creating the file, blocking until it is
+// available.
+func lock(name string) (func(), error) {
+	for {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(name) }, nil
+		} else if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if fi, err := os.Stat(name); err == nil && time.Since(fi.ModTime()) > staleLock {
+			_ = os.Remove(name)
+			continue
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
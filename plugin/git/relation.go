@@ -0,0 +1,82 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/rs/zerolog/log"
+)
+
+// Relations between the old and the new revision of a component
+const (
+	// FastForward means that the old revision is an ancestor of the new one.
+	FastForward = "fast-forward"
+	// Diverged means that both revisions have common ancestors, but the new
+	// revision lacks commits of the old one.
+	Diverged = "diverged"
+	// Unrelated means that the revisions do not have any common ancestor.
+	Unrelated = "unrelated"
+	// Rewritten means that the old revision no longer exists or is not
+	// reachable from any branch, e.g. after a force push.
+	Rewritten = "rewritten"
+)
+
+// relation classifies the relation between the old revision and the commit
+// head of the new revision. It also returns the merge bases, if any.
+func relation(r *git.Repository, oldRev string, head plumbing.Hash) (rel string, bases []plumbing.Hash, err error) {
+	old, ok := resolveRev(r, oldRev)
+	if !ok {
+		log.Warn().Str("rev", oldRev).Msg("Old revision does not exist")
+		return Rewritten, nil, nil
+	}
+
+	o, err := r.CommitObject(old)
+	if err != nil {
+		return "", nil, err
+	}
+	n, err := r.CommitObject(head)
+	if err != nil {
+		return "", nil, err
+	}
+	cs, err := o.MergeBase(n)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, c := range cs {
+		bases = append(bases, c.Hash)
+		if c.Hash == old {
+			rel = FastForward
+		}
+	}
+
+	if rel == "" {
+		branches, _, err := containingRefs(r, old)
+		if err != nil {
+			return "", nil, err
+		}
+		switch {
+		case len(branches) == 0:
+			rel = Rewritten
+		case len(bases) == 0:
+			rel = Unrelated
+		default:
+			rel = Diverged
+		}
+	}
+	log.Debug().Stringer("old", old).Stringer("new", head).Str("relation", rel).
+		Int("bases", len(bases)).Msg("Classified Git revisions")
+	return rel, bases, nil
+}
@@ -0,0 +1,69 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestRelation(t *testing.T) {
+	// A - B - C      main
+	//  \   \
+	//   \   D        release/1.x
+	//    R           not on any branch, e.g. after a force push
+	// X              other
+	g := newGraph(t)
+	g.commit("A")
+	g.commit("B", "A")
+	g.commit("C", "B")
+	g.commit("D", "B")
+	g.commit("R", "A")
+	g.commit("X")
+	g.branch("main", "C")
+	g.branch("release/1.x", "D")
+	g.branch("other", "X")
+
+	tests := []struct {
+		name  string
+		old   string
+		want  string
+		bases []string
+	}{
+		{"ancestor", g.hashes["B"].String(), FastForward, []string{"B"}},
+		{"same commit", "main", FastForward, []string{"C"}},
+		{"diverged branch", "release/1.x", Diverged, []string{"B"}},
+		{"unrelated history", "other", Unrelated, nil},
+		{"unreachable commit", g.hashes["R"].String(), Rewritten, []string{"A"}},
+		{"missing revision", "v0.9", Rewritten, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rel, bases, err := relation(g.r, tt.old, g.hashes["C"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []plumbing.Hash
+			for _, b := range tt.bases {
+				want = append(want, g.hashes[b])
+			}
+			if rel != tt.want || !reflect.DeepEqual(bases, want) {
+				t.Errorf("relation() = %s, %v, want %s, %v", rel, bases, tt.want, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"errors"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/gschauer/heimdall-dev/cfg"
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/rs/zerolog/log"
+)
+
+// repos contains the repositories opened during this run, so that every
+// remote repository is fetched only once.
+var repos = make(map[string]*git.Repository)
+
+// open opens a local repository or fetches a remote one into the mirror
+// cache, see cfg.GetGitCacheDir.
+func open(url string) (*git.Repository, error) {
+	if r, ok := repos[url]; ok {
+		return r, nil
+	}
+
+	r, err := openRepo(url)
+	if err == nil {
+		repos[url] = r
+	}
+	return r, err
+}
+
+// closeRepos releases the repositories opened by open, i.e. the locks of
+// their mirrors. They have to be opened again for further use.
+func closeRepos() {
+	for _, unlock := range mirrorLocks {
+		unlock()
+	}
+	mirrorLocks = nil
+	repos = make(map[string]*git.Repository)
+}
+
+func openRepo(url string) (*git.Repository, error) {
+	ep, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if ep.Protocol == "file" {
+		log.Info().Str("dir", ep.Path).Msg("Opening Git repo")
+		// bare repositories are only found without DetectDotGit
+		r, err := git.PlainOpen(ep.Path)
+		if errors.Is(err, git.ErrRepositoryNotExists) {
+			r, err = git.PlainOpenWithOptions(ep.Path, &git.PlainOpenOptions{DetectDotGit: true})
+		}
+		return r, err
+	}
+
+	a, err := auth(ep)
+	if err != nil {
+		return nil, err
+	}
+	if cache := cfg.GetGitCacheDir(); cache != "" {
+		return openMirror(cache, url, a)
+	}
+	log.Info().Str("URL", url).Msg("Cloning Git repo")
+	return git.Clone(memory.NewStorage(), nil, &git.CloneOptions{URL: url, Auth: a})
+}
+
+// resolve resolves a branch, tag or any other revision such as a hash.
+func resolve(r *git.Repository, rev string) plumbing.Hash {
+	h, ok := resolveRev(r, rev)
+	internal.MustOkMsgf(h, ok, "cannot resolve revision %s", rev)
+	return h
+}
+
+// resolveRev is like resolve, but reports whether the revision exists.
+func resolveRev(r *git.Repository, rev string) (plumbing.Hash, bool) {
+	for _, prefix := range []string{"refs/heads/", "refs/tags/", "refs/remotes/origin/", ""} {
+		if hash, err := r.ResolveRevision(plumbing.Revision(prefix + rev)); err == nil {
+			return *hash, true
+		}
+	}
+	return plumbing.ZeroHash, false
+}
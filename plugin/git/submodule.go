@@ -0,0 +1,121 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/rs/zerolog/log"
+)
+
+// Submodule contains the commits between the old and the new pointer of a
+// submodule, which changed within the release range.
+type Submodule struct {
+	Path     string   `json:"path"`
+	URL      string   `json:"url"`
+	OldHash  string   `json:"oldHash"`
+	NewHash  string   `json:"newHash"`
+	Relation string   `json:"relation"`
+	Commits  []Commit `json:"commits"`
+}
+
+// loadSubmodules expands the changed submodule pointers into the commit
+// ranges of the submodules. Added and removed submodules have no commits.
+// Nested submodules are not expanded.
+func loadSubmodules(r *git.Repository, url string, head plumbing.Hash, fcs []FileChange, v *verifier) ([]Submodule, error) {
+	var sms []Submodule
+	var mods *config.Modules
+	for _, fc := range fcs {
+		if !fc.Submodule || fc.oldHash.IsZero() || fc.newHash.IsZero() {
+			continue
+		}
+		if mods == nil {
+			var err error
+			if mods, err = modules(r, head); err != nil {
+				return nil, err
+			}
+		}
+
+		sm := Submodule{Path: fc.Path, OldHash: fc.oldHash.String(), NewHash: fc.newHash.String()}
+		for _, m := range mods.Submodules {
+			if m.Path == fc.Path {
+				sm.URL = resolveURL(url, m.URL)
+			}
+		}
+		if sm.URL == "" {
+			return nil, errors.New("missing submodule " + fc.Path + " in .gitmodules")
+		}
+		log.Info().Str("path", sm.Path).Str("URL", sm.URL).Msg("Expanding Git submodule")
+
+		sr, err := open(sm.URL)
+		if err != nil {
+			return nil, err
+		}
+		rel, bases, err := relation(sr, sm.OldHash, fc.newHash)
+		if err != nil {
+			return nil, err
+		}
+		sm.Relation = rel
+		if len(bases) > 0 {
+			sm.Commits = loadCommits(sr, fc.newHash, bases, v)
+		}
+		sms = append(sms, sm)
+	}
+	return sms, nil
+}
+
+// modules reads the .gitmodules file of the commit.
+func modules(r *git.Repository, h plumbing.Hash) (*config.Modules, error) {
+	c, err := r.CommitObject(h)
+	if err != nil {
+		return nil, err
+	}
+	f, err := c.File(".gitmodules")
+	if errors.Is(err, object.ErrFileNotFound) {
+		return config.NewModules(), nil
+	} else if err != nil {
+		return nil, err
+	}
+	s, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+	m := config.NewModules()
+	return m, m.Unmarshal([]byte(s))
+}
+
+// resolveURL resolves the URL of a submodule, which may be relative to the
+// URL of the superproject like "../lib.git".
+func resolveURL(base, rel string) string {
+	if !strings.HasPrefix(rel, "./") && !strings.HasPrefix(rel, "../") {
+		return rel
+	}
+	prefix, p := "", base
+	if i := strings.Index(base, "://"); i >= 0 {
+		if j := strings.Index(base[i+3:], "/"); j >= 0 {
+			prefix, p = base[:i+3+j], base[i+3+j:]
+		}
+	} else if i := strings.Index(base, ":"); i >= 0 && !filepath.IsAbs(base) {
+		prefix, p = base[:i+1], base[i+1:]
+	}
+	return prefix + path.Join(p, rel)
+}
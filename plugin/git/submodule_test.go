@@ -0,0 +1,130 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// superCommit creates a commit of the superproject, which contains the
+// .gitmodules file and the submodule "lib" pointing to the given commit.
+func superCommit(t *testing.T, r *git.Repository, gitmodules string, lib plumbing.Hash, parents ...plumbing.Hash) plumbing.Hash {
+	t.Helper()
+	store := func(enc func(o plumbing.EncodedObject) error) plumbing.Hash {
+		o := r.Storer.NewEncodedObject()
+		if err := enc(o); err != nil {
+			t.Fatal(err)
+		}
+		h, err := r.Storer.SetEncodedObject(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	blob := store(func(o plumbing.EncodedObject) error {
+		o.SetType(plumbing.BlobObject)
+		w, err := o.Writer()
+		if err != nil {
+			return err
+		}
+		_, err = w.Write([]byte(gitmodules))
+		return err
+	})
+	tree := store((&object.Tree{Entries: []object.TreeEntry{
+		{Name: ".gitmodules", Mode: filemode.Regular, Hash: blob},
+		{Name: "lib", Mode: filemode.Submodule, Hash: lib},
+	}}).Encode)
+	sig := object.Signature{Name: "Jane", Email: "jane@acme.org", When: time.Now()}
+	return store((&object.Commit{Author: sig, Committer: sig, Message: "bump lib", TreeHash: tree, ParentHashes: parents}).Encode)
+}
+
+func TestLoadSubmodules(t *testing.T) {
+	// the submodule has a side branch, which forked before the old pointer B
+	// and was merged after it
+	// A - B - C - M
+	//  \         /
+	//   S1 - S2 -
+	dir := filepath.Join(t.TempDir(), "lib.git")
+	lr, err := git.PlainInit(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(closeRepos)
+	g := graphOf(t, lr)
+	g.commit("A")
+	g.commit("B", "A")
+	g.commit("C", "B")
+	g.commit("S1", "A")
+	g.commit("S2", "S1")
+	g.commit("M", "C", "S2")
+
+	r, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mods := "[submodule \"lib\"]\n\tpath = lib\n\turl = " + dir + "\n"
+	old := superCommit(t, r, mods, g.hashes["B"])
+	head := superCommit(t, r, mods, g.hashes["M"], old)
+
+	fcs, _, err := diffTrees(r, old, head, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sms, err := loadSubmodules(r, "https://code.local/org/app.git", head, fcs, &verifier{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sms) != 1 {
+		t.Fatalf("loadSubmodules() = %+v, want 1 submodule", sms)
+	}
+
+	sm := sms[0]
+	if sm.Path != "lib" || sm.URL != dir || sm.OldHash != g.hashes["B"].String() || sm.NewHash != g.hashes["M"].String() {
+		t.Errorf("submodule = %+v", sm)
+	}
+	if sm.Relation != FastForward {
+		t.Errorf("relation = %s, want %s", sm.Relation, FastForward)
+	}
+	if got, want := messages(sm.Commits), []string{"C", "M", "S1", "S2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("commits = %v, want %v", got, want)
+	}
+}
+
+func TestResolveURL(t *testing.T) {
+	tests := []struct {
+		base, rel, want string
+	}{
+		{"https://code.local/org/app.git", "../lib.git", "https://code.local/org/lib.git"},
+		{"https://code.local/org/app.git", "./lib.git", "https://code.local/org/app.git/lib.git"},
+		{"git@code.local:org/app.git", "../lib.git", "git@code.local:org/lib.git"},
+		{"/srv/git/app.git", "../lib.git", "/srv/git/lib.git"},
+		{"https://code.local/org/app.git", "https://other.local/lib.git", "https://other.local/lib.git"},
+	}
+	for _, tt := range tests {
+		if got := resolveURL(tt.base, tt.rel); got != tt.want {
+			t.Errorf("resolveURL(%q, %q) = %q, want %q", tt.base, tt.rel, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/gschauer/heimdall-dev/cfg"
+	"github.com/gschauer/heimdall-dev/plugin"
+	"github.com/gschauer/heimdall-dev/release"
+	"github.com/gschauer/heimdall-dev/secret"
+	"github.com/rs/zerolog/log"
+)
+
+// Publish creates an annotated release tag on the evaluated commit of every
+// component, if the release passed the gate and cfg.IsReleaseTagEnabled.
+// The tag is signed with the OpenPGP key in GIT_TAG_SIGNING_KEY, if defined,
+// and pushed to the remote "origin", if cfg.IsReleaseTagPushEnabled.
+// Components within a repository get a tag prefixed with their name.
+func (p *CommitPlugin) Publish(r release.Report, opts plugin.PublishOptions) error {
+	if !cfg.IsReleaseTagEnabled() {
+		return nil
+	} else if r.Verdict == release.Failed {
+		log.Info().Str("verdict", string(r.Verdict)).Msg("Skipping release tags")
+		return nil
+	}
+
+	key, err := signingKey()
+	if err != nil {
+		return err
+	}
+	return p.tag(r, opts, key, cfg.IsReleaseTagPushEnabled())
+}
+
+// tag creates the release tags signed with key, if not nil, and pushes them,
+// if push is set.
+func (p *CommitPlugin) tag(r release.Report, opts plugin.PublishOptions, key *openpgp.Entity, push bool) error {
+	name, email := cfg.GetReleaseTagger()
+	tagOpts := &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: name, Email: email, When: time.Now()},
+		Message: fmt.Sprintf("Release %s %s\n\nVerdict: %s\nReport: sha256:%s", r.Product, r.New.Release, r.Verdict, opts.Digest),
+		SignKey: key,
+	}
+
+	defer closeRepos()
+	for _, c := range p.components {
+		tag := fmt.Sprintf(cfg.GetReleaseTagFormat(), r.New.Release)
+		if c.Path != "" {
+			tag = c.Name + "/" + tag
+		}
+		if opts.DryRun {
+			log.Info().Str("component", c.Name).Str("tag", tag).Str("hash", c.Hash).
+				Bool("signed", key != nil).Bool("push", push).Msg("Would create release tag")
+			continue
+		}
+
+		// the repository is reopened, since mirrors are only locked while in use
+		var err error
+		if c.repo, err = open(c.URL); err != nil {
+			return err
+		}
+		if err = createTag(c.repo, tag, plumbing.NewHash(c.Hash), tagOpts); err != nil {
+			return fmt.Errorf("%s: %w", c.Name, err)
+		}
+		if push {
+			if err = pushTag(c.repo, tag); err != nil {
+				return fmt.Errorf("%s: %w", c.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// createTag creates the annotated tag, unless it already exists for the same
+// commit.
+func createTag(r *git.Repository, tag string, h plumbing.Hash, opts *git.CreateTagOptions) error {
+	if ref, err := r.Tag(tag); err == nil {
+		if t, ok := peel(r, ref.Hash()); ok && t == h {
+			log.Info().Str("tag", tag).Msg("Release tag already exists")
+			return nil
+		}
+		return fmt.Errorf("tag %s already exists for another commit", tag)
+	}
+	o := *opts
+	if _, err := r.CreateTag(tag, h, &o); err != nil {
+		return err
+	}
+	log.Info().Str("tag", tag).Stringer("hash", h).Msg("Created release tag")
+	return nil
+}
+
+func pushTag(r *git.Repository, tag string) error {
+	rm, err := r.Remote(git.DefaultRemoteName)
+	if errors.Is(err, git.ErrRemoteNotFound) {
+		log.Warn().Str("tag", tag).Msg("Cannot push release tag without remote")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	ep, err := transport.NewEndpoint(rm.Config().URLs[0])
+	if err != nil {
+		return err
+	}
+	a, err := auth(ep)
+	if err != nil {
+		return err
+	}
+	spec := config.RefSpec(plumbing.NewTagReferenceName(tag) + ":" + plumbing.NewTagReferenceName(tag))
+	err = r.Push(&git.PushOptions{RemoteName: git.DefaultRemoteName, RefSpecs: []config.RefSpec{spec}, Auth: a})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		err = nil
+	}
+	if err == nil {
+		log.Info().Str("tag", tag).Str("URL", ep.String()).Msg("Pushed release tag")
+	}
+	return err
+}
+
+// signingKey reads the armored OpenPGP private key in GIT_TAG_SIGNING_KEY
+// and decrypts it with GIT_TAG_SIGNING_KEY_PASSWORD, if necessary.
+func signingKey() (*openpgp.Entity, error) {
+	name := os.Getenv("GIT_TAG_SIGNING_KEY")
+	if name == "" {
+		return nil, nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	es, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	} else if len(es) == 0 || es[0].PrivateKey == nil {
+		return nil, fmt.Errorf("%s: no private key found", name)
+	}
+
+	e := es[0]
+	if e.PrivateKey.Encrypted {
+		pass := []byte(secret.Getenv("GIT_TAG_SIGNING_KEY_PASSWORD"))
+		if err = e.PrivateKey.Decrypt(pass); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, sk := range e.Subkeys {
+			if sk.PrivateKey != nil && sk.PrivateKey.Encrypted {
+				if err = sk.PrivateKey.Decrypt(pass); err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+			}
+		}
+	}
+	return e, nil
+}
@@ -0,0 +1,152 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gschauer/heimdall-dev/plugin"
+	"github.com/gschauer/heimdall-dev/release"
+)
+
+// newRepos creates a bare repository and a clone with a commit, which is
+// pushed to the bare repository. It returns the paths of both and the hashes
+// of the commits.
+func newRepos(t *testing.T, commits int) (string, string, []plumbing.Hash) {
+	t.Helper()
+	dir := t.TempDir()
+	bare, work := filepath.Join(dir, "remote.git"), filepath.Join(dir, "work")
+	if _, err := git.PlainInit(bare, true); err != nil {
+		t.Fatal(err)
+	}
+	r, err := git.PlainInit(work, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{bare}}); err != nil {
+		t.Fatal(err)
+	}
+
+	wt, _ := r.Worktree()
+	var hs []plumbing.Hash
+	for i := 0; i < commits; i++ {
+		f := filepath.Join(work, "file.txt")
+		if err = os.WriteFile(f, []byte(strings.Repeat("x", i+1)), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = wt.Add("file.txt"); err != nil {
+			t.Fatal(err)
+		}
+		sig := &object.Signature{Name: "Jane", Email: "jane@acme.org", When: time.Now()}
+		h, err := wt.Commit("commit", &git.CommitOptions{Author: sig, Committer: sig})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hs = append(hs, h)
+	}
+	if err = r.Push(&git.PushOptions{RemoteName: "origin"}); err != nil {
+		t.Fatal(err)
+	}
+	return bare, work, hs
+}
+
+func report(verdict release.Status) release.Report {
+	return release.Report{Product: "ZZZ", New: release.Info{Name: "ZZZ", Release: "1.4"}, Verdict: verdict}
+}
+
+func TestTagCreatesAndPushes(t *testing.T) {
+	bare, work, hs := newRepos(t, 1)
+	p := &CommitPlugin{components: map[string]*Component{
+		"work": {Name: "work", URL: work, Hash: hs[0].String()},
+	}}
+	if err := p.tag(report(release.OK), plugin.PublishOptions{Digest: "abc"}, nil, true); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{work, bare} {
+		r, _ := git.PlainOpen(dir)
+		ref, err := r.Tag("release/1.4")
+		if err != nil {
+			t.Fatalf("%s: %v", dir, err)
+		}
+		tag, err := r.TagObject(ref.Hash())
+		if err != nil {
+			t.Fatalf("%s: tag is not annotated: %v", dir, err)
+		}
+		if tag.Target != hs[0] {
+			t.Errorf("%s: tag points to %s, want %s", dir, tag.Target, hs[0])
+		}
+		if !strings.Contains(tag.Message, "Verdict: OK") || !strings.Contains(tag.Message, "sha256:abc") {
+			t.Errorf("%s: unexpected message %q", dir, tag.Message)
+		}
+	}
+}
+
+func TestTagDryRun(t *testing.T) {
+	bare, work, hs := newRepos(t, 1)
+	p := &CommitPlugin{components: map[string]*Component{
+		"work": {Name: "work", URL: work, Hash: hs[0].String()},
+	}}
+	if err := p.tag(report(release.OK), plugin.PublishOptions{DryRun: true}, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{work, bare} {
+		r, _ := git.PlainOpen(dir)
+		if _, err := r.Tag("release/1.4"); err == nil {
+			t.Errorf("%s: dry run created a tag", dir)
+		}
+	}
+}
+
+func TestTagMonorepoComponent(t *testing.T) {
+	_, work, hs := newRepos(t, 1)
+	p := &CommitPlugin{components: map[string]*Component{
+		"api": {Name: "api", URL: work, Path: "services/api", Hash: hs[0].String()},
+	}}
+	if err := p.tag(report(release.Warn), plugin.PublishOptions{}, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := git.PlainOpen(work)
+	if _, err := r.Tag("api/release/1.4"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTagExisting(t *testing.T) {
+	_, work, hs := newRepos(t, 2)
+	p := &CommitPlugin{components: map[string]*Component{
+		"work": {Name: "work", URL: work, Hash: hs[0].String()},
+	}}
+	if err := p.tag(report(release.OK), plugin.PublishOptions{}, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	// re-runs for the same commit succeed
+	if err := p.tag(report(release.OK), plugin.PublishOptions{}, nil, false); err != nil {
+		t.Errorf("re-run: %v", err)
+	}
+
+	p.components["work"].Hash = hs[1].String()
+	if err := p.tag(report(release.OK), plugin.PublishOptions{}, nil, false); err == nil {
+		t.Error("tag of another commit was overwritten")
+	}
+}
@@ -0,0 +1,231 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gschauer/heimdall-dev/cfg"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	beginPGP = "-----BEGIN PGP SIGNATURE-----"
+	beginSSH = "-----BEGIN SSH SIGNATURE-----"
+	// sshNamespace is the namespace of SSH signatures created by Git.
+	sshNamespace = "git"
+)
+
+// Verification is the result of verifying the signature of a commit or tag.
+type Verification struct {
+	Signed bool `json:"signed"`
+	// SignatureValid is true, if the signature was made by a trusted key.
+	SignatureValid bool `json:"signatureValid"`
+	// Signer is the user ID of the OpenPGP key or the principal of the SSH key.
+	Signer string `json:"signer"`
+}
+
+// verifier verifies OpenPGP and SSH signatures against the trusted keys
+// configured by cfg.GetGitKeyring and cfg.GetGitAllowedSigners.
+type verifier struct {
+	keyring openpgp.EntityList
+	signers []allowedSigner
+}
+
+type allowedSigner struct {
+	principal string
+	key       ssh.PublicKey
+}
+
+func newVerifier() (*verifier, error) {
+	v := &verifier{}
+	if f, err := os.Open(cfg.GetGitKeyring()); err == nil {
+		defer func() { _ = f.Close() }()
+		if v.keyring, err = openpgp.ReadArmoredKeyRing(f); err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.GetGitKeyring(), err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if f, err := os.Open(cfg.GetGitAllowedSigners()); err == nil {
+		defer func() { _ = f.Close() }()
+		if v.signers, err = readAllowedSigners(f); err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.GetGitAllowedSigners(), err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	log.Debug().Int("pgp", len(v.keyring)).Int("ssh", len(v.signers)).Msg("Loaded trusted keys")
+	return v, nil
+}
+
+// commit verifies the signature of the commit.
+func (v *verifier) commit(c *object.Commit) Verification {
+	if c.PGPSignature == "" {
+		return Verification{}
+	}
+	o := &plumbing.MemoryObject{}
+	if err := c.EncodeWithoutSignature(o); err != nil {
+		log.Warn().Err(err).Stringer("hash", c.Hash).Msg("Cannot encode commit")
+		return Verification{Signed: true}
+	}
+	r, _ := o.Reader()
+	payload, _ := io.ReadAll(r)
+	return v.verify(c.Hash, payload, c.PGPSignature)
+}
+
+// tag verifies the signature of the annotated tag. The signature is appended
+// to the raw tag object, so that it can be split off without re-encoding.
+func (v *verifier) tag(r *git.Repository, t *object.Tag) Verification {
+	o, err := r.Storer.EncodedObject(plumbing.TagObject, t.Hash)
+	if err != nil {
+		return Verification{}
+	}
+	rd, _ := o.Reader()
+	data, _ := io.ReadAll(rd)
+
+	i := bytes.Index(data, []byte(beginPGP))
+	if i < 0 {
+		i = bytes.Index(data, []byte(beginSSH))
+	}
+	if i < 0 {
+		return Verification{}
+	}
+	return v.verify(t.Hash, data[:i], string(data[i:]))
+}
+
+func (v *verifier) verify(h plumbing.Hash, payload []byte, sig string) Verification {
+	res := Verification{Signed: true}
+	var signer string
+	var err error
+	if strings.HasPrefix(sig, beginSSH) {
+		signer, err = v.verifySSH(payload, sig)
+	} else {
+		var e *openpgp.Entity
+		if e, err = openpgp.CheckArmoredDetachedSignature(v.keyring, bytes.NewReader(payload), strings.NewReader(sig), nil); err == nil {
+			signer = e.PrimaryIdentity().Name
+		}
+	}
+
+	if err != nil {
+		log.Debug().Err(err).Stringer("hash", h).Msg("Invalid signature")
+		return res
+	}
+	// the signer is only reported for valid signatures
+	res.Signer, res.SignatureValid = signer, true
+	return res
+}
+
+// verifySSH verifies an SSH signature as specified by PROTOCOL.sshsig of
+// OpenSSH and returns the principal of the signing key, if it is valid.
+func (v *verifier) verifySSH(payload []byte, armored string) (string, error) {
+	blk, _ := pem.Decode([]byte(armored))
+	if blk == nil || !bytes.HasPrefix(blk.Bytes, []byte("SSHSIG")) {
+		return "", errors.New("malformed SSH signature")
+	}
+	var s struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Signature     []byte
+	}
+	if err := ssh.Unmarshal(blk.Bytes[6:], &s); err != nil {
+		return "", err
+	}
+	if s.Namespace != sshNamespace {
+		return "", fmt.Errorf("unexpected namespace %q", s.Namespace)
+	}
+
+	pub, err := ssh.ParsePublicKey(s.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	principal := ""
+	for _, a := range v.signers {
+		if bytes.Equal(a.key.Marshal(), pub.Marshal()) {
+			principal = a.principal
+			break
+		}
+	}
+	if principal == "" {
+		return "", errors.New("untrusted SSH key " + ssh.FingerprintSHA256(pub))
+	}
+
+	var digest []byte
+	switch s.HashAlgorithm {
+	case "sha256":
+		h := sha256.Sum256(payload)
+		digest = h[:]
+	case "sha512":
+		h := sha512.Sum512(payload)
+		digest = h[:]
+	default:
+		return "", fmt.Errorf("unsupported hash algorithm %q", s.HashAlgorithm)
+	}
+	signed := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Hash          []byte
+	}{s.Namespace, s.Reserved, s.HashAlgorithm, digest})...)
+
+	sig := &ssh.Signature{}
+	if err = ssh.Unmarshal(s.Signature, sig); err != nil {
+		return "", err
+	}
+	if err = pub.Verify(signed, sig); err != nil {
+		return "", err
+	}
+	return principal, nil
+}
+
+// readAllowedSigners parses a file in the format of ssh-keygen's
+// allowed_signers, i.e. "principals [options] keytype key [comment]".
+func readAllowedSigners(r io.Reader) (as []allowedSigner, err error) {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		l := strings.TrimSpace(sc.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		principals, rest, _ := strings.Cut(l, " ")
+		k, _, _, _, err := ssh.ParseAuthorizedKey([]byte(rest))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range strings.Split(principals, ",") {
+			as = append(as, allowedSigner{p, k})
+		}
+	}
+	return as, sc.Err()
+}
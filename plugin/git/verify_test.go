@@ -0,0 +1,200 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package git
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+func newPGPKey(t *testing.T, name string) *openpgp.Entity {
+	t.Helper()
+	e, err := openpgp.NewEntity(name, "", strings.ToLower(name)+"@acme.org", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func newSSHKey(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, k, _ := ed25519.GenerateKey(rand.Reader)
+	s, err := ssh.NewSignerFromKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func signPGP(t *testing.T, e *openpgp.Entity, payload []byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&buf, e, bytes.NewReader(payload), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// signSSH creates an armored signature like "ssh-keygen -Y sign -n NAMESPACE".
+func signSSH(t *testing.T, s ssh.Signer, namespace string, payload []byte) string {
+	t.Helper()
+	h := sha512.Sum512(payload)
+	signed := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Hash          []byte
+	}{namespace, nil, "sha512", h[:]})...)
+	sig, err := s.Sign(rand.Reader, signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Signature     []byte
+	}{1, s.PublicKey().Marshal(), namespace, nil, "sha512", ssh.Marshal(sig)})...)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "SSH SIGNATURE", Bytes: blob}))
+}
+
+// payload returns the commit as it is signed by Git.
+func payload(t *testing.T, c *object.Commit) []byte {
+	t.Helper()
+	o := &plumbing.MemoryObject{}
+	if err := c.EncodeWithoutSignature(o); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := o.Reader()
+	data, _ := io.ReadAll(r)
+	return data
+}
+
+func TestVerifyCommit(t *testing.T) {
+	jane, mallory := newPGPKey(t, "Jane"), newPGPKey(t, "Mallory")
+	janeSSH, mallorySSH := newSSHKey(t), newSSHKey(t)
+	v := &verifier{
+		keyring: openpgp.EntityList{jane},
+		signers: []allowedSigner{{"jane@acme.org", janeSSH.PublicKey()}},
+	}
+
+	tests := []struct {
+		name string
+		// sign returns the signature of the payload
+		sign func(payload []byte) string
+		// tamper changes the commit after signing
+		tamper bool
+		want   Verification
+	}{
+		{"unsigned", nil, false, Verification{}},
+		{"PGP", func(p []byte) string { return signPGP(t, jane, p) }, false, Verification{true, true, "Jane <jane@acme.org>"}},
+		{"PGP untrusted", func(p []byte) string { return signPGP(t, mallory, p) }, false, Verification{Signed: true}},
+		{"PGP tampered", func(p []byte) string { return signPGP(t, jane, p) }, true, Verification{Signed: true}},
+		{"SSH", func(p []byte) string { return signSSH(t, janeSSH, "git", p) }, false, Verification{true, true, "jane@acme.org"}},
+		{"SSH untrusted", func(p []byte) string { return signSSH(t, mallorySSH, "git", p) }, false, Verification{Signed: true}},
+		{"SSH tampered", func(p []byte) string { return signSSH(t, janeSSH, "git", p) }, true, Verification{Signed: true}},
+		{"SSH namespace", func(p []byte) string { return signSSH(t, janeSSH, "file", p) }, false, Verification{Signed: true}},
+		{"SSH malformed", func(p []byte) string { return beginSSH + "\nxyz\n-----END SSH SIGNATURE-----\n" }, false, Verification{Signed: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sig := object.Signature{Name: "Jane", Email: "jane@acme.org"}
+			c := &object.Commit{Author: sig, Committer: sig, Message: "feat: add endpoint\n"}
+			if tt.sign != nil {
+				c.PGPSignature = tt.sign(payload(t, c))
+			}
+			if tt.tamper {
+				c.Message = "feat: add backdoor\n"
+			}
+			if got := v.commit(c); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyTag(t *testing.T) {
+	jane := newPGPKey(t, "Jane")
+	g := newGraph(t)
+	h := g.commit("A")
+	sig := &object.Signature{Name: "Jane", Email: "jane@acme.org"}
+	signed, err := g.r.CreateTag("v1", h, &git.CreateTagOptions{Tagger: sig, Message: "v1", SignKey: jane})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned, err := g.r.CreateTag("v2", h, &git.CreateTagOptions{Tagger: sig, Message: "v2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		ref     *plumbing.Reference
+		keyring openpgp.EntityList
+		want    Verification
+	}{
+		{"trusted", signed, openpgp.EntityList{jane}, Verification{true, true, "Jane <jane@acme.org>"}},
+		{"untrusted", signed, nil, Verification{Signed: true}},
+		{"unsigned", unsigned, openpgp.EntityList{jane}, Verification{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, err := g.r.TagObject(tt.ref.Hash())
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := &verifier{keyring: tt.keyring}
+			if got := v.tag(g.r, tag); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tag() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadAllowedSigners(t *testing.T) {
+	k := newSSHKey(t).PublicKey()
+	key := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(k)))
+	in := "# maintainers\n\njane@acme.org,joe@acme.org " + key + " laptop\n"
+
+	as, err := readAllowedSigners(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(as) != 2 || as[0].principal != "jane@acme.org" || as[1].principal != "joe@acme.org" {
+		t.Fatalf("readAllowedSigners() = %+v", as)
+	}
+	if !bytes.Equal(as[1].key.Marshal(), k.Marshal()) {
+		t.Error("unexpected key")
+	}
+
+	if _, err = readAllowedSigners(strings.NewReader("jane@acme.org not-a-key")); err == nil {
+		t.Error("invalid key accepted")
+	}
+}
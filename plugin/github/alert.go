@@ -0,0 +1,221 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v49/github"
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/rs/zerolog/log"
+)
+
+// Kinds of security alerts.
+const (
+	CodeScanning   = "code-scanning"
+	Dependabot     = "dependabot"
+	SecretScanning = "secret-scanning"
+)
+
+// Alert is a code scanning, Dependabot or secret scanning alert, which is
+// open or was dismissed. Fixed alerts are omitted.
+type Alert struct {
+	Repo   string `json:"repo"`
+	Ref    string `json:"ref"`
+	Kind   string `json:"kind"`
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+	// Rule is the rule ID of the code scanning tool, the advisory ID or the
+	// secret type.
+	Rule        string `json:"rule"`
+	Description string `json:"description"`
+	// Severity is "critical", "high", "medium" or "low". Code scanning alerts
+	// without security severity have "error", "warning" or "note", and leaked
+	// secrets are always critical.
+	Severity string    `json:"severity"`
+	State    string    `json:"state"`
+	Created  time.Time `json:"created"`
+	// AgeDays is the age of the alert in days, when the facts were loaded.
+	AgeDays         int    `json:"ageDays"`
+	DismissedReason string `json:"dismissedReason"`
+}
+
+// AlertScan tells whether the alerts of a kind could be loaded for a
+// repository. Alerts are unavailable, if the scanning is disabled or the token
+// lacks the permission to read them.
+type AlertScan struct {
+	Repo      string `json:"repo"`
+	Ref       string `json:"ref"`
+	Kind      string `json:"kind"`
+	Available bool   `json:"available"`
+	// Reason is the response of GitHub for unavailable alerts, e.g.
+	// "403 Resource not accessible by integration".
+	Reason string `json:"reason"`
+}
+
+// loadAlerts loads the code scanning alerts of the released branch.
+func (p *RepoPlugin) loadAlerts(owner, repo, branch string) {
+	log.Info().Str("repo", owner+"/"+repo).Str("branch", branch).Msg("Loading code scanning alerts")
+	as, reason := p.codeScanningAlerts(owner, repo, branch)
+	p.addAlerts(owner, repo, branch, CodeScanning, as, reason)
+}
+
+// loadRepoAlerts loads the Dependabot and secret scanning alerts, which only
+// exist for the default branch, but apply to every release of the repository.
+func (p *RepoPlugin) loadRepoAlerts(owner, repo string) {
+	log.Info().Str("repo", owner+"/"+repo).Msg("Loading Dependabot and secret scanning alerts")
+	ref := p.repoInfos[owner+"/"+repo].DefaultBranch
+	as, reason := p.dependabotAlerts(owner, repo)
+	p.addAlerts(owner, repo, ref, Dependabot, as, reason)
+	as, reason = p.secretScanningAlerts(owner, repo)
+	p.addAlerts(owner, repo, ref, SecretScanning, as, reason)
+}
+
+func (p *RepoPlugin) addAlerts(owner, repo, ref, kind string, as []Alert, reason string) {
+	for i := range as {
+		as[i].Repo = owner + "/" + repo
+		as[i].AgeDays = int(time.Since(as[i].Created).Hours() / 24)
+	}
+	p.alerts = append(p.alerts, as...)
+	p.scans = append(p.scans, AlertScan{owner + "/" + repo, ref, kind, reason == "", reason})
+}
+
+func (p *RepoPlugin) codeScanningAlerts(owner, repo, branch string) ([]Alert, string) {
+	var as []Alert
+	for _, state := range []string{"open", "dismissed"} {
+		opts := &github.AlertListOptions{State: state, Ref: "refs/heads/" + branch, ListOptions: github.ListOptions{PerPage: 100}}
+		for {
+			ls, resp, err := p.client.CodeScanning.ListAlertsForRepo(context.Background(), owner, repo, opts)
+			if reason, ok := unavailable(CodeScanning, owner, repo, resp, err); ok {
+				return nil, reason
+			}
+			internal.MustNoErr(err)
+			for _, a := range ls {
+				sev := a.GetRule().GetSecuritySeverityLevel()
+				if sev == "" {
+					sev = a.GetRule().GetSeverity()
+				}
+				as = append(as, Alert{
+					Ref:             branch,
+					Kind:            CodeScanning,
+					Number:          a.GetNumber(),
+					URL:             a.GetHTMLURL(),
+					Rule:            a.GetRule().GetID(),
+					Description:     a.GetRule().GetDescription(),
+					Severity:        sev,
+					State:           a.GetState(),
+					Created:         a.GetCreatedAt().Time,
+					DismissedReason: a.GetDismissedReason(),
+				})
+			}
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.ListOptions.Page = resp.NextPage
+		}
+	}
+	return as, ""
+}
+
+func (p *RepoPlugin) dependabotAlerts(owner, repo string) ([]Alert, string) {
+	var as []Alert
+	state := "open,dismissed"
+	opts := &github.ListAlertsOptions{State: &state, ListCursorOptions: github.ListCursorOptions{PerPage: 100}}
+	for {
+		ls, resp, err := p.client.Dependabot.ListRepoAlerts(context.Background(), owner, repo, opts)
+		if reason, ok := unavailable(Dependabot, owner, repo, resp, err); ok {
+			return nil, reason
+		}
+		internal.MustNoErr(err)
+		for _, a := range ls {
+			adv := a.GetSecurityAdvisory()
+			as = append(as, Alert{
+				Ref:             p.repoInfos[owner+"/"+repo].DefaultBranch,
+				Kind:            Dependabot,
+				Number:          a.GetNumber(),
+				URL:             a.GetHTMLURL(),
+				Rule:            adv.GetGHSAID(),
+				Description:     adv.GetSummary(),
+				Severity:        adv.GetSeverity(),
+				State:           a.GetState(),
+				Created:         a.GetCreatedAt().Time,
+				DismissedReason: a.GetDismissedReason(),
+			})
+		}
+		if resp.After == "" {
+			break
+		}
+		opts.After = resp.After
+	}
+	return as, ""
+}
+
+func (p *RepoPlugin) secretScanningAlerts(owner, repo string) ([]Alert, string) {
+	var as []Alert
+	opts := &github.SecretScanningAlertListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		ls, resp, err := p.client.SecretScanning.ListAlertsForRepo(context.Background(), owner, repo, opts)
+		if reason, ok := unavailable(SecretScanning, owner, repo, resp, err); ok {
+			return nil, reason
+		}
+		internal.MustNoErr(err)
+		for _, a := range ls {
+			// revoked secrets are considered fixed
+			if a.GetResolution() == "revoked" {
+				continue
+			}
+			state := a.GetState()
+			if state == "resolved" {
+				state = "dismissed"
+			}
+			as = append(as, Alert{
+				Ref:             p.repoInfos[owner+"/"+repo].DefaultBranch,
+				Kind:            SecretScanning,
+				Number:          a.GetNumber(),
+				URL:             a.GetHTMLURL(),
+				Rule:            a.GetSecretType(),
+				Severity:        "critical",
+				State:           state,
+				Created:         a.GetCreatedAt().Time,
+				DismissedReason: a.GetResolution(),
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.ListOptions.Page = resp.NextPage
+	}
+	return as, ""
+}
+
+// unavailable reports whether the scanning is disabled for the repository or
+// not accessible by the token and returns the reason.
+func unavailable(kind, owner, repo string, resp *github.Response, err error) (string, bool) {
+	var rle *github.RateLimitError
+	if resp == nil || (resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusForbidden) || errors.As(err, &rle) {
+		return "", false
+	}
+	reason := strconv.Itoa(resp.StatusCode)
+	var er *github.ErrorResponse
+	if errors.As(err, &er) && er.Message != "" {
+		reason += " " + er.Message
+	}
+	log.Warn().Str("repo", owner+"/"+repo).Str("kind", kind).Str("reason", reason).Msg("Security alerts unavailable")
+	return reason, true
+}
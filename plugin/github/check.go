@@ -0,0 +1,292 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package github
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/v49/github"
+	"github.com/gschauer/heimdall-dev"
+	"github.com/gschauer/heimdall-dev/cfg"
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/plugin"
+	"github.com/gschauer/heimdall-dev/plugin/git"
+	"github.com/gschauer/heimdall-dev/release"
+	"github.com/rs/zerolog/log"
+)
+
+// maxAnnotations is the maximum number of annotations per request.
+const maxAnnotations = 50
+
+// target is an evaluated commit, which receives the outcome of the gate.
+type target struct {
+	owner, repo, sha string
+	// name of the check run or the context of the commit status
+	name string
+}
+
+// Publish posts the outcome of the gate as check run on the evaluated commit
+// of every component, if cfg.IsGitHubCheckEnabled. Commit statuses are used
+// instead, if the token cannot create check runs, e.g. a personal access
+// token. If cfg.IsGitHubCommentEnabled, the summary is also commented on the
+// open pull requests of the evaluated commits. Re-runs update the check run
+// and the comment.
+func (p *RepoPlugin) Publish(r release.Report, opts plugin.PublishOptions) error {
+	if !cfg.IsGitHubCheckEnabled() && !cfg.IsGitHubCommentEnabled() {
+		return nil
+	} else if p.client == nil {
+		log.Warn().Msg("Skipping GitHub checks without credentials")
+		return nil
+	}
+
+	gp, ok := plugin.Find[*git.CommitPlugin]()
+	if !ok {
+		log.Warn().Msg("Skipping GitHub checks without Git plugin")
+		return nil
+	}
+	summary, err := renderSummary(r, opts.Digest)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range gp.Components() {
+		owner, repo, ok := p.ownerRepo(c.URL)
+		if !ok {
+			continue
+		}
+		t := target{owner, repo, c.Hash, cfg.GetGitHubCheckName()}
+		if c.Path != "" {
+			t.name += " / " + c.Name
+		}
+		if opts.DryRun {
+			log.Info().Str("repo", owner+"/"+repo).Str("hash", c.Hash).Str("name", t.name).
+				Str("verdict", string(r.Verdict)).Msg("Would publish GitHub check")
+			continue
+		}
+
+		if cfg.IsGitHubCheckEnabled() {
+			if err = p.publishCheck(t, r, summary); err != nil {
+				return fmt.Errorf("%s: %w", c.Name, err)
+			}
+		}
+		if cfg.IsGitHubCommentEnabled() {
+			if err = p.comment(t, summary); err != nil {
+				return fmt.Errorf("%s: %w", c.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// publishCheck publishes a check run for the target. Re-runs update the
+// existing check run with the name, so that the commit does not accumulate
+// duplicates. GitHub appends the annotations of an update to the existing
+// ones, though. It falls back to a commit status, if check runs are not
+// permitted.
+func (p *RepoPlugin) publishCheck(t target, r release.Report, summary string) error {
+	ctx := context.Background()
+	title := fmt.Sprintf("%s %s: %s", r.Product, r.New.Release, r.Verdict)
+	output := &github.CheckRunOutput{Title: &title, Summary: &summary}
+
+	as := annotations(r.Checks)
+	output.Annotations = as[:internal.Min(len(as), maxAnnotations)]
+	rs, resp, err := p.client.Checks.ListCheckRunsForRef(ctx, t.owner, t.repo, t.sha, &github.ListCheckRunsOptions{
+		CheckName: &t.name,
+		Filter:    github.String("latest"),
+	})
+	if forbidden(resp) {
+		return p.publishStatus(t, r, title)
+	} else if err != nil {
+		return err
+	}
+
+	var cr *github.CheckRun
+	if len(rs.CheckRuns) > 0 {
+		cr, resp, err = p.client.Checks.UpdateCheckRun(ctx, t.owner, t.repo, rs.CheckRuns[0].GetID(), github.UpdateCheckRunOptions{
+			Name:       t.name,
+			Status:     github.String("completed"),
+			Conclusion: github.String(conclusion(r.Verdict)),
+			Output:     output,
+		})
+	} else {
+		cr, resp, err = p.client.Checks.CreateCheckRun(ctx, t.owner, t.repo, github.CreateCheckRunOptions{
+			Name:       t.name,
+			HeadSHA:    t.sha,
+			Status:     github.String("completed"),
+			Conclusion: github.String(conclusion(r.Verdict)),
+			Output:     output,
+		})
+	}
+	if forbidden(resp) {
+		return p.publishStatus(t, r, title)
+	}
+	// further annotations are appended in chunks
+	for i := maxAnnotations; err == nil && i < len(as); i += maxAnnotations {
+		output.Annotations = as[i:internal.Min(len(as), i+maxAnnotations)]
+		_, _, err = p.client.Checks.UpdateCheckRun(ctx, t.owner, t.repo, cr.GetID(), github.UpdateCheckRunOptions{Name: t.name, Output: output})
+	}
+	if err != nil {
+		return err
+	}
+	log.Info().Str("repo", t.owner+"/"+t.repo).Str("hash", t.sha).Str("url", cr.GetHTMLURL()).Msg("Published GitHub check run")
+	return nil
+}
+
+// publishStatus sets the commit status of the target, which replaces the
+// status of an earlier run with the same context.
+func (p *RepoPlugin) publishStatus(t target, r release.Report, title string) error {
+	state := map[release.Status]string{release.OK: "success", release.Warn: "success", release.Failed: "failure"}[r.Verdict]
+	if len(title) > 140 {
+		title = title[:140]
+	}
+	_, _, err := p.client.Repositories.CreateStatus(context.Background(), t.owner, t.repo, t.sha, &github.RepoStatus{
+		State:       &state,
+		Context:     &t.name,
+		Description: &title,
+	})
+	if err == nil {
+		log.Info().Str("repo", t.owner+"/"+t.repo).Str("hash", t.sha).Str("state", state).Msg("Published GitHub commit status")
+	}
+	return err
+}
+
+// comment creates or updates the summary comment on the open pull requests,
+// whose head is the target commit.
+func (p *RepoPlugin) comment(t target, summary string) error {
+	ctx := context.Background()
+	marker := fmt.Sprintf("<!-- %s -->", t.name)
+	body := marker + "\n" + summary
+
+	opts := &github.PullRequestListOptions{State: "open", ListOptions: github.ListOptions{PerPage: 100}}
+	var prs []*github.PullRequest
+	for {
+		ls, resp, err := p.client.PullRequests.ListPullRequestsWithCommit(ctx, t.owner, t.repo, t.sha, opts)
+		if err != nil {
+			return err
+		}
+		prs = append(prs, ls...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	for _, pr := range prs {
+		if pr.GetHead().GetSHA() != t.sha {
+			continue
+		}
+		n := pr.GetNumber()
+		id, err := p.markedComment(t, n, marker)
+		if err != nil {
+			return err
+		}
+
+		if id != 0 {
+			_, _, err = p.client.Issues.EditComment(ctx, t.owner, t.repo, id, &github.IssueComment{Body: &body})
+		} else {
+			_, _, err = p.client.Issues.CreateComment(ctx, t.owner, t.repo, n, &github.IssueComment{Body: &body})
+		}
+		if err != nil {
+			return err
+		}
+		log.Info().Str("repo", t.owner+"/"+t.repo).Int("number", n).Bool("updated", id != 0).Msg("Commented on pull request")
+	}
+	return nil
+}
+
+// markedComment returns the ID of the comment starting with the marker or 0.
+func (p *RepoPlugin) markedComment(t target, n int, marker string) (int64, error) {
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		cs, resp, err := p.client.Issues.ListComments(context.Background(), t.owner, t.repo, n, opts)
+		if err != nil {
+			return 0, err
+		}
+		for _, c := range cs {
+			if strings.HasPrefix(c.GetBody(), marker) {
+				return c.GetID(), nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return 0, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func renderSummary(r release.Report, digest string) (string, error) {
+	text, err := fs.ReadFile(heimdall.StaticFS, "plugin/report/summary.md")
+	if err != nil {
+		return "", err
+	}
+	cell := strings.NewReplacer("|", "\\|", "\r", "", "\n", "<br>")
+	tmpl, err := template.New("summary.md").Funcs(template.FuncMap{"cell": cell.Replace}).Parse(string(text))
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, map[string]any{"Report": r, "Digest": digest})
+	return b.String(), err
+}
+
+// annotations returns an annotation for every check, which did not pass.
+// They are not tied to a file of the repository.
+func annotations(cs []release.Check) []*github.CheckRunAnnotation {
+	as := []*github.CheckRunAnnotation{}
+	for _, c := range cs {
+		if c.Status == release.OK {
+			continue
+		}
+		msg := c.Comment
+		if msg == "" {
+			msg = string(c.Status)
+		}
+		level := "failure"
+		if c.Status == release.Warn || c.Type == release.Optional {
+			level = "warning"
+		}
+		as = append(as, &github.CheckRunAnnotation{
+			Path:            github.String("."),
+			StartLine:       github.Int(1),
+			EndLine:         github.Int(1),
+			AnnotationLevel: &level,
+			Title:           github.String(c.Name),
+			Message:         &msg,
+		})
+	}
+	return as
+}
+
+func conclusion(v release.Status) string {
+	switch v {
+	case release.OK:
+		return "success"
+	case release.Warn:
+		return "neutral"
+	default:
+		return "failure"
+	}
+}
+
+// forbidden reports whether the token lacks the permission for the request.
+func forbidden(resp *github.Response) bool {
+	return resp != nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound)
+}
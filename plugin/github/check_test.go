@@ -0,0 +1,66 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package github
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gschauer/heimdall-dev/release"
+)
+
+func TestPublishCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		runs   string
+		want   []string
+	}{
+		{"first run", http.StatusOK, `{"total_count": 0, "check_runs": []}`,
+			[]string{"GET /check-runs", "POST /api/v3/repos/org/zzz-web/check-runs"}},
+		{"re-run", http.StatusOK, `{"total_count": 1, "check_runs": [{"id": 5, "name": "heimdall"}]}`,
+			[]string{"GET /check-runs", "PATCH /api/v3/repos/org/zzz-web/check-runs/5"}},
+		{"check runs not permitted", http.StatusForbidden, `{"message": "Resource not accessible by integration"}`,
+			[]string{"GET /check-runs", "POST /api/v3/repos/org/zzz-web/statuses/abc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			p := newTestPlugin(t, func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/org/zzz-web/commits/abc/check-runs":
+					if q := r.URL.Query().Get("check_name"); q != "heimdall" {
+						t.Errorf("check_name = %q, want heimdall", q)
+					}
+					got = append(got, "GET /check-runs")
+					w.WriteHeader(tt.status)
+					_, _ = w.Write([]byte(tt.runs))
+				default:
+					got = append(got, r.Method+" "+r.URL.Path)
+					_, _ = w.Write([]byte(`{"id": 5}`))
+				}
+			})
+
+			r := release.Report{Product: "zzz", New: release.Info{Release: "1.4"}, Verdict: release.OK}
+			if err := p.publishCheck(target{"org", "zzz-web", "abc", "heimdall"}, r, "summary"); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("requests = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package github
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/go-github/v49/github"
+	"github.com/gschauer/heimdall-dev/cfg"
+	"github.com/gschauer/heimdall-dev/secret"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
+)
+
+var errNoCredentials = errors.New("neither GITHUB_TOKEN nor GITHUB_APP_ID defined")
+
+// newClient creates a client for github.com or, if GITHUB_API_URL is defined,
+// for GitHub Enterprise Server. It authenticates as installation of a GitHub
+// App, if GITHUB_APP_ID is defined, or by the (personal) access token in
+// GITHUB_TOKEN. It also returns the host of the repositories.
+func newClient() (*github.Client, string, error) {
+	rt := &retryTransport{base: http.DefaultTransport}
+	var auth http.RoundTripper
+	if id := os.Getenv("GITHUB_APP_ID"); id != "" {
+		app, err := newJWTTransport(rt, id, os.Getenv("GITHUB_APP_PRIVATE_KEY"))
+		if err != nil {
+			return nil, "", err
+		}
+		appClient, _, err := newAPIClient(&http.Client{Transport: app})
+		if err != nil {
+			return nil, "", err
+		}
+		inst, _ := strconv.ParseInt(os.Getenv("GITHUB_APP_INSTALLATION_ID"), 10, 64)
+		auth = &installationTransport{base: rt, apps: appClient.Apps, id: inst}
+	} else if t := secret.Getenv("GITHUB_TOKEN"); t != "" {
+		auth = &oauth2.Transport{Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: t}), Base: rt}
+	} else {
+		return nil, "", errNoCredentials
+	}
+	return newAPIClient(&http.Client{Transport: auth})
+}
+
+// newAPIClient creates a client for the API given by GITHUB_API_URL, e.g.
+// "https://ghe.local/api/v3" or "https://ghe.local/api". It defaults to
+// github.com.
+func newAPIClient(hc *http.Client) (*github.Client, string, error) {
+	api := strings.TrimSuffix(os.Getenv("GITHUB_API_URL"), "/")
+	if api == "" || api == "https://api.github.com" {
+		return github.NewClient(hc), "github.com", nil
+	}
+
+	base := strings.TrimSuffix(strings.TrimSuffix(api, "/v3"), "/api")
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, "", err
+	}
+	c, err := github.NewEnterpriseClient(base+"/api/v3/", base+"/api/uploads/", hc)
+	return c, u.Hostname(), err
+}
+
+// jwtTransport authenticates as GitHub App by a JWT, which is signed by the
+// private key of the app.
+type jwtTransport struct {
+	base  http.RoundTripper
+	appID string
+	key   *rsa.PrivateKey
+}
+
+func newJWTTransport(base http.RoundTripper, appID, keyFile string) (*jwtTransport, error) {
+	pem, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("GITHUB_APP_PRIVATE_KEY: %w", err)
+	}
+	// the key is read from a file rather than the environment, hence it must
+	// be registered explicitly to be redacted from logs and reports
+	secret.Register(string(pem), strings.TrimSpace(string(pem)))
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, fmt.Errorf("GITHUB_APP_PRIVATE_KEY: %w", err)
+	}
+	return &jwtTransport{base, appID, key}, nil
+}
+
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// backdate the token to allow for clock drift, GitHub accepts at most 10 minutes
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(9 * time.Minute)),
+		Issuer:    t.appID,
+	}
+	s, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(t.key)
+	if err != nil {
+		return nil, err
+	}
+
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+s)
+	return t.base.RoundTrip(r)
+}
+
+// installationTransport authenticates as installation of a GitHub App.
+// Installation tokens are created on demand and renewed before they expire.
+// If the installation ID is unknown, the app must have exactly one
+// installation.
+type installationTransport struct {
+	base http.RoundTripper
+	apps *github.AppsService
+	id   int64
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (t *installationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tok, err := t.installationToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "token "+tok)
+	return t.base.RoundTrip(r)
+}
+
+func (t *installationTransport) installationToken(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && time.Now().Add(time.Minute).Before(t.expires) {
+		return t.token, nil
+	}
+
+	if t.id == 0 {
+		is, _, err := t.apps.ListInstallations(ctx, nil)
+		if err != nil {
+			return "", err
+		} else if len(is) != 1 {
+			return "", fmt.Errorf("GITHUB_APP_INSTALLATION_ID required for %d installations", len(is))
+		}
+		t.id = is[0].GetID()
+	}
+
+	it, _, err := t.apps.CreateInstallationToken(ctx, t.id, nil)
+	if err != nil {
+		return "", err
+	}
+	t.token, t.expires = it.GetToken(), it.GetExpiresAt()
+	secret.Register(t.token)
+	log.Debug().Int64("installation", t.id).Time("expires", t.expires).Msg("Created GitHub installation token")
+	return t.token, nil
+}
+
+// retryTransport waits for the reset of exceeded rate limits. Requests, which
+// hit the primary or secondary rate limit, are retried. Successful responses,
+// which exhaust the rate limit, are delayed until the reset, since subsequent
+// requests would fail otherwise.
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for i := 0; ; i++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		wait, ok := rateLimitWait(resp)
+		if !ok || wait > cfg.GetGitHubMaxRateLimitWait() {
+			return resp, nil
+		}
+
+		limited := resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests
+		log.Warn().Str("URL", req.URL.Path).Int("status", resp.StatusCode).Dur("wait", wait).Msg("GitHub rate limit exceeded")
+		if !limited || i >= cfg.GetGitHubRetries() {
+			time.Sleep(wait)
+			return resp, nil
+		}
+
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		time.Sleep(wait)
+		if req.Body != nil && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// rateLimitWait returns the duration until the rate limit is reset, if it is
+// exhausted.
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil {
+			return time.Duration(n) * time.Second, true
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if d := time.Until(time.Unix(reset, 0)) + time.Second; d > 0 {
+		return d, true
+	}
+	return 0, true
+}
@@ -0,0 +1,232 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/gschauer/heimdall-dev/secret"
+)
+
+// setenv sets the GitHub environment variables and clears the others.
+func setenv(t *testing.T, vars map[string]string) {
+	for _, k := range []string{"GITHUB_API_URL", "GITHUB_TOKEN", "GITHUB_APP_ID", "GITHUB_APP_PRIVATE_KEY", "GITHUB_APP_INSTALLATION_ID"} {
+		t.Setenv(k, vars[k])
+	}
+}
+
+// serveRepo responds to the request of the repository org/zzz-web, if it is
+// authorized by auth.
+func serveRepo(t *testing.T, w http.ResponseWriter, r *http.Request, auth string) {
+	if r.URL.Path != "/api/v3/repos/org/zzz-web" {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if got := r.Header.Get("Authorization"); got != auth {
+		t.Errorf("Authorization = %q, want %q", got, auth)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	_, _ = w.Write([]byte(`{"id": 1, "full_name": "org/zzz-web", "default_branch": "main"}`))
+}
+
+func TestNewClientToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveRepo(t, w, r, "Bearer pat")
+	}))
+	defer srv.Close()
+	setenv(t, map[string]string{"GITHUB_API_URL": srv.URL + "/api/v3", "GITHUB_TOKEN": "pat"})
+
+	c, host, err := newClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host != "127.0.0.1" {
+		t.Errorf("host = %q, want 127.0.0.1", host)
+	}
+	r, _, err := c.Repositories.Get(context.Background(), "org", "zzz-web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.GetFullName() != "org/zzz-web" {
+		t.Errorf("full name = %q", r.GetFullName())
+	}
+}
+
+func TestNewClientGitHubCom(t *testing.T) {
+	setenv(t, map[string]string{"GITHUB_TOKEN": "pat"})
+	c, host, err := newClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host != "github.com" || c.BaseURL.String() != "https://api.github.com/" {
+		t.Errorf("host = %q, base URL = %s", host, c.BaseURL)
+	}
+}
+
+func TestNewAPIClientEnterprise(t *testing.T) {
+	for _, api := range []string{"https://ghe.local/api/v3", "https://ghe.local/api/v3/", "https://ghe.local/api", "https://ghe.local"} {
+		setenv(t, map[string]string{"GITHUB_API_URL": api})
+		c, host, err := newAPIClient(http.DefaultClient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if host != "ghe.local" || c.BaseURL.String() != "https://ghe.local/api/v3/" {
+			t.Errorf("%s: host = %q, base URL = %s", api, host, c.BaseURL)
+		}
+	}
+}
+
+func TestNewClientNoCredentials(t *testing.T) {
+	setenv(t, nil)
+	if _, _, err := newClient(); !errors.Is(err, errNoCredentials) {
+		t.Errorf("err = %v, want %v", err, errNoCredentials)
+	}
+}
+
+func TestNewClientApp(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	bs := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err = os.WriteFile(keyFile, bs, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/v3/app/") {
+			s := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			var claims jwt.RegisteredClaims
+			if _, err := jwt.ParseWithClaims(s, &claims, func(*jwt.Token) (any, error) { return &key.PublicKey, nil }); err != nil {
+				t.Errorf("invalid JWT: %v", err)
+			} else if claims.Issuer != "42" {
+				t.Errorf("issuer = %q, want 42", claims.Issuer)
+			}
+		}
+		switch r.URL.Path {
+		case "/api/v3/app/installations":
+			_, _ = w.Write([]byte(`[{"id": 7}]`))
+		case "/api/v3/app/installations/7/access_tokens":
+			tokens++
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]any{"token": "inst", "expires_at": time.Now().Add(time.Hour)})
+		default:
+			serveRepo(t, w, r, "token inst")
+		}
+	}))
+	defer srv.Close()
+	setenv(t, map[string]string{"GITHUB_API_URL": srv.URL + "/api/v3", "GITHUB_APP_ID": "42", "GITHUB_APP_PRIVATE_KEY": keyFile})
+
+	c, _, err := newClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, _, err = c.Repositories.Get(context.Background(), "org", "zzz-web"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tokens != 1 {
+		t.Errorf("created %d installation tokens, want 1", tokens)
+	}
+	if got := secret.Redact("key: " + string(bs)); strings.Contains(got, "PRIVATE KEY") {
+		t.Errorf("Redact() = %q, want the private key redacted", got)
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		header   map[string]string
+		failures int
+		requests int
+		want     int
+	}{
+		{"secondary rate limit", http.StatusForbidden, map[string]string{"Retry-After": "0"}, 1, 2, http.StatusOK},
+		{"too many requests", http.StatusTooManyRequests, map[string]string{"Retry-After": "0"}, 2, 3, http.StatusOK},
+		{"primary rate limit", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "0"}, 1, 2, http.StatusOK},
+		{"retries exceeded", http.StatusTooManyRequests, map[string]string{"Retry-After": "0"}, 10, 4, http.StatusTooManyRequests},
+		{"forbidden", http.StatusForbidden, nil, 1, 1, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if n++; n <= tt.failures {
+					for k, v := range tt.header {
+						w.Header().Set(k, v)
+					}
+					w.WriteHeader(tt.status)
+				}
+			}))
+			defer srv.Close()
+
+			hc := &http.Client{Transport: &retryTransport{base: http.DefaultTransport}}
+			resp, err := hc.Post(srv.URL, "text/plain", strings.NewReader("body"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.want || n != tt.requests {
+				t.Errorf("status = %d after %d requests, want %d after %d", resp.StatusCode, n, tt.want, tt.requests)
+			}
+		})
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Unix()
+	tests := []struct {
+		header map[string]string
+		min    time.Duration
+		ok     bool
+	}{
+		{map[string]string{"Retry-After": "30"}, 30 * time.Second, true},
+		{map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(reset, 10)}, 50 * time.Second, true},
+		{map[string]string{"X-RateLimit-Remaining": "10", "X-RateLimit-Reset": strconv.FormatInt(reset, 10)}, 0, false},
+		{nil, 0, false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		for k, v := range tt.header {
+			resp.Header.Set(k, v)
+		}
+		d, ok := rateLimitWait(resp)
+		if ok != tt.ok || d < tt.min || (tt.ok && d > tt.min+time.Minute) {
+			t.Errorf("%v: wait = %s, %t", tt.header, d, ok)
+		}
+	}
+}
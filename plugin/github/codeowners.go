@@ -0,0 +1,325 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package github
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v49/github"
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/plugin"
+	"github.com/gschauer/heimdall-dev/plugin/git"
+	"github.com/gschauer/heimdall-dev/plugin/scm"
+	"github.com/rs/zerolog/log"
+)
+
+// codeownersPaths are the locations of the CODEOWNERS file in the order of
+// precedence.
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// Ownership relates the changed files of a component to their code owners
+// and the approvals of the pull requests, which changed them.
+type Ownership struct {
+	Component string `json:"component"`
+	Repo      string `json:"repo"`
+	// File is the path of the CODEOWNERS file, empty if there is none.
+	File string `json:"file"`
+	// Unowned are the changed files without owner.
+	Unowned []string        `json:"unowned"`
+	Files   []OwnedFile     `json:"files"`
+	Owners  []OwnerApproval `json:"owners"`
+}
+
+// OwnedFile is a changed file with code owners. It is approved, if every
+// pull request changing the file was approved by one of its owners. Files
+// changed by any commit without pull request are never approved.
+type OwnedFile struct {
+	Path         string   `json:"path"`
+	Owners       []string `json:"owners"`
+	PullRequests []int    `json:"pullRequests"`
+	// Commits are the commits changing the file without pull request.
+	Commits  []string `json:"commits"`
+	Approved bool     `json:"approved"`
+}
+
+// OwnerApproval is a code owner, e.g. "@org/team", "@user" or an email
+// address, who had to approve the changes of its files. Owners given by email
+// cannot be matched with approvers.
+type OwnerApproval struct {
+	Owner        string   `json:"owner"`
+	Files        []string `json:"files"`
+	PullRequests []int    `json:"pullRequests"`
+	// Unapproved are the pull requests without approval of the owner.
+	Unapproved []int `json:"unapproved"`
+	// Commits are the commits changing the files without pull request.
+	Commits  []string `json:"commits"`
+	Approved bool     `json:"approved"`
+}
+
+// codeownersRule is a pattern of the CODEOWNERS file and its owners.
+type codeownersRule struct {
+	re     *regexp.Regexp
+	owners []string
+}
+
+// loadOwnership loads the code owners of the changed files of every
+// component at the evaluated commit.
+func (p *RepoPlugin) loadOwnership() {
+	gp, ok := plugin.Find[*git.CommitPlugin]()
+	if !ok {
+		log.Warn().Msg("Skipping code owners without Git plugin")
+		return
+	}
+
+	prs := p.PullRequests()
+	members := make(map[string][]string)
+	for name, c := range gp.Components() {
+		owner, repo, ok := p.ownerRepo(c.URL)
+		if !ok {
+			continue
+		}
+		file, rules := p.codeowners(owner, repo, c.Hash)
+		o := Ownership{Component: name, Repo: owner + "/" + repo, File: file,
+			Unowned: []string{}, Files: []OwnedFile{}, Owners: []OwnerApproval{}}
+
+		// pull requests and direct commits changing the files of the component
+		changedBy := make(map[string][]*scm.PullRequest)
+		pushedBy := make(map[string][]string)
+		seen := make(map[int]bool)
+		for _, co := range c.Commits {
+			// merge commits only combine changes, which are checked by themselves
+			if len(prs[co.Hash]) == 0 && !co.Merge {
+				for _, f := range p.commitFiles(owner, repo, co.Hash) {
+					pushedBy[f] = append(pushedBy[f], co.Hash)
+				}
+			}
+			for _, pr := range prs[co.Hash] {
+				if pr.Repo != o.Repo || seen[pr.Number] {
+					continue
+				}
+				seen[pr.Number] = true
+				for _, f := range p.pullRequestFiles(owner, repo, pr.Number) {
+					changedBy[f] = append(changedBy[f], pr)
+				}
+			}
+		}
+
+		owners := make(map[string]*OwnerApproval)
+		for _, fc := range c.Changes {
+			ows := matchOwners(rules, fc.Path)
+			if len(ows) == 0 {
+				o.Unowned = append(o.Unowned, fc.Path)
+				continue
+			}
+			f := OwnedFile{Path: fc.Path, Owners: ows, PullRequests: []int{}, Commits: []string{},
+				Approved: len(changedBy[fc.Path]) > 0 && len(pushedBy[fc.Path]) == 0}
+			f.Commits = append(f.Commits, pushedBy[fc.Path]...)
+			for _, pr := range changedBy[fc.Path] {
+				f.PullRequests = append(f.PullRequests, pr.Number)
+				approved := false
+				for _, ow := range ows {
+					ok := p.approvedBy(ow, pr.Approvers, members)
+					approved = approved || ok
+					oa := owners[ow]
+					if oa == nil {
+						oa = newOwnerApproval(ow)
+						owners[ow] = oa
+					}
+					if !internal.Contains(oa.PullRequests, pr.Number) {
+						oa.PullRequests = append(oa.PullRequests, pr.Number)
+						if !ok {
+							oa.Unapproved = append(oa.Unapproved, pr.Number)
+						}
+					}
+				}
+				f.Approved = f.Approved && approved
+			}
+			for _, ow := range ows {
+				if owners[ow] == nil {
+					owners[ow] = newOwnerApproval(ow)
+				}
+				owners[ow].Files = append(owners[ow].Files, fc.Path)
+				for _, h := range f.Commits {
+					if !internal.Contains(owners[ow].Commits, h) {
+						owners[ow].Commits = append(owners[ow].Commits, h)
+					}
+				}
+			}
+			o.Files = append(o.Files, f)
+		}
+		for _, oa := range owners {
+			// changes without pull request were not approved by anyone
+			oa.Approved = len(oa.Unapproved) == 0 && len(oa.PullRequests) > 0 && len(oa.Commits) == 0
+			o.Owners = append(o.Owners, *oa)
+		}
+		sort.Slice(o.Owners, func(i, j int) bool { return o.Owners[i].Owner < o.Owners[j].Owner })
+		p.ownership = append(p.ownership, o)
+	}
+	sort.Slice(p.ownership, func(i, j int) bool { return p.ownership[i].Component < p.ownership[j].Component })
+}
+
+func newOwnerApproval(owner string) *OwnerApproval {
+	return &OwnerApproval{Owner: owner, Files: []string{}, PullRequests: []int{}, Unapproved: []int{}, Commits: []string{}}
+}
+
+// commitFiles returns the files changed by the commit.
+func (p *RepoPlugin) commitFiles(owner, repo, sha string) []string {
+	var fs []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		c, resp := internal.Must2(p.client.Repositories.GetCommit(context.Background(), owner, repo, sha, opts))
+		for _, f := range c.Files {
+			fs = append(fs, f.GetFilename())
+			if f.GetPreviousFilename() != "" {
+				fs = append(fs, f.GetPreviousFilename())
+			}
+		}
+		if resp.NextPage == 0 {
+			return fs
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// codeowners returns the path and the rules of the CODEOWNERS file at the
+// commit.
+func (p *RepoPlugin) codeowners(owner, repo, sha string) (string, []codeownersRule) {
+	for _, path := range codeownersPaths {
+		fc, _, resp, err := p.client.Repositories.GetContents(context.Background(), owner, repo, path, &github.RepositoryContentGetOptions{Ref: sha})
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		internal.MustNoErr(err)
+		log.Debug().Str("repo", owner+"/"+repo).Str("path", path).Msg("Loading code owners")
+		return path, parseCodeowners(internal.Must(fc.GetContent()))
+	}
+	return "", nil
+}
+
+func parseCodeowners(text string) []codeownersRule {
+	var rs []codeownersRule
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for sc.Scan() {
+		l := strings.TrimSpace(sc.Text())
+		if i := strings.Index(l, " #"); i >= 0 {
+			l = l[:i]
+		}
+		fs := strings.Fields(l)
+		if len(fs) == 0 || strings.HasPrefix(fs[0], "#") {
+			continue
+		}
+		rs = append(rs, codeownersRule{codeownersRegexp(fs[0]), fs[1:]})
+	}
+	return rs
+}
+
+// codeownersRegexp converts a pattern of the CODEOWNERS file, which follows
+// the rules of gitignore, into a regular expression. Patterns without "/"
+// match at any depth, and patterns also match the files within a matching
+// directory, except for patterns ending with "/*".
+func codeownersRegexp(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	p := strings.TrimSuffix(pattern, "/")
+	if !strings.Contains(p, "/") {
+		sb.WriteString("(?:.*/)?")
+	}
+	p = strings.TrimPrefix(p, "/")
+	sb.WriteString(internal.GlobExpr(p))
+	if !strings.HasSuffix(p, "/*") {
+		sb.WriteString("(?:/.*)?")
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+// matchOwners returns the owners of the last matching rule.
+func matchOwners(rs []codeownersRule, path string) []string {
+	for i := len(rs) - 1; i >= 0; i-- {
+		if rs[i].re.MatchString(path) {
+			return rs[i].owners
+		}
+	}
+	return nil
+}
+
+// approvedBy reports whether one of the approvers is the owner or a member of
+// the owning team. Members are cached by team.
+func (p *RepoPlugin) approvedBy(owner string, approvers []string, members map[string][]string) bool {
+	if !strings.HasPrefix(owner, "@") {
+		return false
+	}
+	login := strings.TrimPrefix(owner, "@")
+	users := []string{login}
+	if o, team, ok := strings.Cut(login, "/"); ok {
+		if _, ok := members[login]; !ok {
+			members[login] = p.teamMembers(o, team)
+		}
+		users = members[login]
+	}
+	for _, a := range approvers {
+		for _, u := range users {
+			if strings.EqualFold(a, u) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *RepoPlugin) teamMembers(org, team string) []string {
+	var logins []string
+	opts := &github.TeamListTeamMembersOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		us, resp, err := p.client.Teams.ListTeamMembersBySlug(context.Background(), org, team, opts)
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+			log.Warn().Str("team", org+"/"+team).Int("status", resp.StatusCode).Msg("Cannot list team members")
+			return logins
+		}
+		internal.MustNoErr(err)
+		for _, u := range us {
+			logins = append(logins, u.GetLogin())
+		}
+		if resp.NextPage == 0 {
+			return logins
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// pullRequestFiles returns the paths of the files changed by a pull request,
+// including the previous paths of renamed files.
+func (p *RepoPlugin) pullRequestFiles(owner, repo string, n int) []string {
+	var fs []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		cfs, resp := internal.Must2(p.client.PullRequests.ListFiles(context.Background(), owner, repo, n, opts))
+		for _, f := range cfs {
+			fs = append(fs, f.GetFilename())
+			if f.GetPreviousFilename() != "" {
+				fs = append(fs, f.GetPreviousFilename())
+			}
+		}
+		if resp.NextPage == 0 {
+			return fs
+		}
+		opts.Page = resp.NextPage
+	}
+}
@@ -0,0 +1,116 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package github
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCodeownersRegexp(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/tool/main.go", true},
+		{"*.go", "main.golden", false},
+		{"docs/", "docs/guide/intro.md", true},
+		{"docs/", "api/docs/intro.md", true},
+		{"/docs/", "api/docs/intro.md", false},
+		{"docs/*", "docs/intro.md", true},
+		{"docs/*", "docs/guide/intro.md", false},
+		{"apps/**/build", "apps/web/ui/build/out.js", true},
+		{"**/build", "build/out.js", true},
+		{"/db/migration", "db/migration/V1.sql", true},
+		{"/db/migration", "db/migrations/V1.sql", false},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file10.txt", false},
+		{"a+b.txt", "a+b.txt", true},
+	}
+	for _, tt := range tests {
+		if got := codeownersRegexp(tt.pattern).MatchString(tt.path); got != tt.want {
+			t.Errorf("codeownersRegexp(%q).MatchString(%q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMatchOwners(t *testing.T) {
+	rs := parseCodeowners(`# default owners
+*                 @org/core
+*.sql             @org/dba jane@acme.org   # inline comment
+/db/migration/    @org/migrations
+docs/
+`)
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"main.go", []string{"@org/core"}},
+		{"schema/tables.sql", []string{"@org/dba", "jane@acme.org"}},
+		// the last matching rule takes precedence
+		{"db/migration/V1.sql", []string{"@org/migrations"}},
+		// rules without owners unset the owners
+		{"docs/index.md", []string{}},
+	}
+	for _, tt := range tests {
+		if got := matchOwners(rs, tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("matchOwners(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestApprovedBy(t *testing.T) {
+	requests := 0
+	p := newTestPlugin(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/orgs/org/teams/core/members":
+			requests++
+			_, _ = w.Write([]byte(`[{"login": "jane"}, {"login": "joe"}]`))
+		case "/api/v3/orgs/org/teams/secret/members":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message": "Resource not accessible by integration"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	tests := []struct {
+		name      string
+		owner     string
+		approvers []string
+		want      bool
+	}{
+		{"user", "@jane", []string{"Jane"}, true},
+		{"other user", "@jane", []string{"joe"}, false},
+		{"team member", "@org/core", []string{"bob", "joe"}, true},
+		{"no team member", "@org/core", []string{"bob"}, false},
+		{"unreadable team", "@org/secret", []string{"jane"}, false},
+		{"email", "jane@acme.org", []string{"jane"}, false},
+	}
+	members := make(map[string][]string)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.approvedBy(tt.owner, tt.approvers, members); got != tt.want {
+				t.Errorf("approvedBy(%q, %v) = %v, want %v", tt.owner, tt.approvers, got, tt.want)
+			}
+		})
+	}
+	if requests != 1 {
+		t.Errorf("listed the team members %d times, want 1", requests)
+	}
+}
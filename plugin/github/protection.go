@@ -0,0 +1,179 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v49/github"
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/plugin/scm"
+	"github.com/rs/zerolog/log"
+)
+
+// rule is an active rule for a branch, see
+// https://docs.github.com/en/rest/repos/rules#get-rules-for-a-branch
+type rule struct {
+	Type       string `json:"type"`
+	RulesetID  int64  `json:"ruleset_id"`
+	Source     string `json:"ruleset_source"`
+	Parameters struct {
+		RequiredApprovingReviewCount int  `json:"required_approving_review_count"`
+		DismissStaleReviewsOnPush    bool `json:"dismiss_stale_reviews_on_push"`
+		RequireCodeOwnerReview       bool `json:"require_code_owner_review"`
+		RequireLastPushApproval      bool `json:"require_last_push_approval"`
+		RequiredStatusChecks         []struct {
+			Context string `json:"context"`
+		} `json:"required_status_checks"`
+		StrictRequiredStatusChecksPolicy bool `json:"strict_required_status_checks_policy"`
+	} `json:"parameters"`
+}
+
+// repoPath returns the full name of a GitHub repository, e.g. "org/repo".
+func (p *RepoPlugin) repoPath(u string) (string, bool) {
+	owner, repo, ok := p.ownerRepo(u)
+	return owner + "/" + repo, ok
+}
+
+// branchExists reports whether the branch exists in the repository.
+func (p *RepoPlugin) branchExists(fullName, branch string) bool {
+	owner, repo, _ := strings.Cut(fullName, "/")
+	_, resp, err := p.client.Repositories.GetBranch(context.Background(), owner, repo, branch, true)
+	if err == nil {
+		return true
+	} else if resp == nil || resp.StatusCode != http.StatusNotFound {
+		internal.MustNoErr(err)
+	}
+	return false
+}
+
+func (p *RepoPlugin) defaultBranch(fullName string) string {
+	return p.repoInfos[fullName].DefaultBranch
+}
+
+func (p *RepoPlugin) loadProtection(owner, repo, branch string) scm.BranchProtection {
+	log.Info().Str("repo", owner+"/"+repo).Str("branch", branch).Msg("Loading branch protection")
+	ctx := context.Background()
+	bp := scm.BranchProtection{Repo: owner + "/" + repo, Branch: branch, RequiredChecks: []string{}, Rulesets: []string{},
+		ForcePushAllowed: true, DeletionAllowed: true}
+
+	pr, resp, err := p.client.Repositories.GetBranchProtection(ctx, owner, repo, branch)
+	switch {
+	case err == nil:
+		bp.Protected = true
+		if r := pr.RequiredPullRequestReviews; r != nil {
+			bp.RequiredReviews = r.RequiredApprovingReviewCount
+			bp.DismissStaleReviews = r.DismissStaleReviews
+			bp.CodeOwnerReviews = r.RequireCodeOwnerReviews
+			bp.LastPushApproval = r.RequireLastPushApproval
+		}
+		if c := pr.RequiredStatusChecks; c != nil {
+			bp.StrictChecks = c.Strict
+			bp.RequiredChecks = append(bp.RequiredChecks, c.Contexts...)
+			for _, rc := range c.Checks {
+				bp.RequiredChecks = append(bp.RequiredChecks, rc.Context)
+			}
+		}
+		bp.EnforceAdmins = pr.EnforceAdmins != nil && pr.EnforceAdmins.Enabled
+		bp.LinearHistory = pr.RequireLinearHistory != nil && pr.RequireLinearHistory.Enabled
+		bp.ForcePushAllowed = pr.AllowForcePushes != nil && pr.AllowForcePushes.Enabled
+		bp.DeletionAllowed = pr.AllowDeletions != nil && pr.AllowDeletions.Enabled
+
+		sig, resp, err := p.client.Repositories.GetSignaturesProtectedBranch(ctx, owner, repo, branch)
+		if forbidden(resp) {
+			log.Warn().Str("repo", bp.Repo).Str("branch", branch).Msg("Cannot read required signatures")
+			bp.Unknown = true
+		} else {
+			internal.MustNoErr(err)
+		}
+		bp.SignedCommits = sig.GetEnabled()
+	case errors.Is(err, github.ErrBranchNotProtected):
+	case forbidden(resp):
+		// read-only tokens get 403 or 404 instead of ErrBranchNotProtected
+		log.Warn().Str("repo", bp.Repo).Str("branch", branch).Msg("Cannot read branch protection")
+		bp.Unknown = true
+	default:
+		internal.MustNoErr(err)
+	}
+
+	rulesets := make(map[int64]bool)
+	for _, r := range p.branchRules(owner, repo, branch) {
+		bp.Protected = true
+		if !rulesets[r.RulesetID] {
+			rulesets[r.RulesetID] = true
+			bp.Rulesets = append(bp.Rulesets, p.rulesetName(owner, repo, r))
+		}
+		switch r.Type {
+		case "pull_request":
+			ps := r.Parameters
+			bp.RequiredReviews = internal.Max(bp.RequiredReviews, ps.RequiredApprovingReviewCount)
+			bp.DismissStaleReviews = bp.DismissStaleReviews || ps.DismissStaleReviewsOnPush
+			bp.CodeOwnerReviews = bp.CodeOwnerReviews || ps.RequireCodeOwnerReview
+			bp.LastPushApproval = bp.LastPushApproval || ps.RequireLastPushApproval
+		case "required_status_checks":
+			bp.StrictChecks = bp.StrictChecks || r.Parameters.StrictRequiredStatusChecksPolicy
+			for _, c := range r.Parameters.RequiredStatusChecks {
+				if !internal.Contains(bp.RequiredChecks, c.Context) {
+					bp.RequiredChecks = append(bp.RequiredChecks, c.Context)
+				}
+			}
+		case "required_signatures":
+			bp.SignedCommits = true
+		case "required_linear_history":
+			bp.LinearHistory = true
+		case "non_fast_forward":
+			bp.ForcePushAllowed = false
+		case "deletion":
+			bp.DeletionAllowed = false
+		}
+	}
+	return bp
+}
+
+// branchRules returns the active rules of all rulesets for a branch. GitHub
+// Enterprise Server without rulesets responds with 404.
+func (p *RepoPlugin) branchRules(owner, repo, branch string) []rule {
+	var rs []rule
+	for page := 1; ; page++ {
+		var l []rule
+		u := fmt.Sprintf("repos/%s/%s/rules/branches/%s?per_page=100&page=%d", owner, repo, url.PathEscape(branch), page)
+		if !p.get(u, &l) {
+			return rs
+		}
+		rs = append(rs, l...)
+		if len(l) < 100 {
+			return rs
+		}
+	}
+}
+
+// rulesetName returns the name of the ruleset of a rule.
+func (p *RepoPlugin) rulesetName(owner, repo string, r rule) string {
+	u := fmt.Sprintf("repos/%s/%s/rulesets/%d", owner, repo, r.RulesetID)
+	req := internal.Must(p.client.NewRequest(http.MethodGet, u, nil))
+	var rs struct {
+		Name string `json:"name"`
+	}
+	if _, err := p.client.Do(context.Background(), req, &rs); err != nil || rs.Name == "" {
+		// organization rulesets are not readable by the repository
+		return fmt.Sprintf("%s#%d", r.Source, r.RulesetID)
+	}
+	return rs.Name
+}
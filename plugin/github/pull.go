@@ -0,0 +1,243 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/google/go-github/v49/github"
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/plugin"
+	"github.com/gschauer/heimdall-dev/plugin/git"
+	"github.com/gschauer/heimdall-dev/plugin/scm"
+	"github.com/rs/zerolog/log"
+)
+
+// provenance contains the pull requests of all commits in the release range.
+type provenance struct {
+	Commits      []scm.CommitProvenance `json:"commits"`
+	PullRequests []*scm.PullRequest     `json:"pullRequests"`
+}
+
+// loadProvenance maps the commits of the Git plugin to their pull requests.
+func (p *RepoPlugin) loadProvenance() {
+	gp, ok := plugin.Find[*git.CommitPlugin]()
+	if !ok {
+		log.Warn().Msg("Skipping pull requests without Git plugin")
+		return
+	}
+
+	// merge commits by pull request, which are shared by the components of a monorepo
+	prs, mergeCommits := make(map[string]*scm.PullRequest), make(map[string]string)
+	for name, c := range gp.Components() {
+		p.loadCommits(name, c.URL, c.Commits, prs, mergeCommits)
+		for _, sm := range c.Submodules {
+			p.loadCommits(name, sm.URL, sm.Commits, prs, mergeCommits)
+		}
+	}
+	for _, pr := range prs {
+		p.prov.PullRequests = append(p.prov.PullRequests, pr)
+	}
+	sort.Slice(p.prov.PullRequests, func(i, j int) bool {
+		a, b := p.prov.PullRequests[i], p.prov.PullRequests[j]
+		return a.Repo < b.Repo || (a.Repo == b.Repo && a.Number < b.Number)
+	})
+}
+
+func (p *RepoPlugin) loadCommits(comp, url string, cs []git.Commit, prs map[string]*scm.PullRequest, mergeCommits map[string]string) {
+	owner, repo, ok := p.ownerRepo(url)
+	if !ok {
+		return
+	}
+	inPR := make(map[string]string)
+	for _, c := range cs {
+		cp := scm.CommitProvenance{Hash: c.Hash, Component: comp, Repo: owner + "/" + repo, PullRequests: []int{}}
+		for _, pr := range p.pullRequestsWithCommit(owner, repo, c.Hash) {
+			if pr.MergedAt == nil {
+				continue
+			}
+			key := prKey(cp.Repo, pr.GetNumber())
+			if prs[key] == nil {
+				prs[key], mergeCommits[key] = p.loadPullRequest(owner, repo, pr.GetNumber())
+			}
+			cp.PullRequests = append(cp.PullRequests, pr.GetNumber())
+			inPR[c.Hash] = key
+		}
+		p.prov.Commits = append(p.prov.Commits, cp)
+	}
+
+	// GitHub does not record the merge method, but it can be derived from the
+	// merge commit: merge commits have two parents, and rebased commits are
+	// preceded by another commit of the same pull request.
+	for _, c := range cs {
+		key, ok := inPR[c.Hash]
+		if !ok || mergeCommits[key] != c.Hash {
+			continue
+		}
+		switch {
+		case len(c.Parents) > 1:
+			prs[key].MergeMethod = scm.Merge
+		case len(c.Parents) == 1 && inPR[c.Parents[0]] == key:
+			prs[key].MergeMethod = scm.Rebase
+		default:
+			prs[key].MergeMethod = scm.Squash
+		}
+	}
+}
+
+// pullRequestsWithCommit returns all pull requests containing the commit.
+func (p *RepoPlugin) pullRequestsWithCommit(owner, repo, sha string) []*github.PullRequest {
+	var prs []*github.PullRequest
+	opts := &github.PullRequestListOptions{State: "all", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		ls, resp := internal.Must2(p.client.PullRequests.ListPullRequestsWithCommit(context.Background(), owner, repo, sha, opts))
+		prs = append(prs, ls...)
+		if resp.NextPage == 0 {
+			return prs
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// loadPullRequest returns the pull request and its merge commit.
+func (p *RepoPlugin) loadPullRequest(owner, repo string, n int) (*scm.PullRequest, string) {
+	ctx := context.Background()
+	pr, _ := internal.Must2(p.client.PullRequests.Get(ctx, owner, repo, n))
+	log.Debug().Str("repo", owner+"/"+repo).Int("number", n).Msg("Loading pull request")
+	res := &scm.PullRequest{
+		Repo:       owner + "/" + repo,
+		Number:     n,
+		URL:        pr.GetHTMLURL(),
+		Title:      pr.GetTitle(),
+		Author:     pr.GetUser().GetLogin(),
+		MergedBy:   pr.GetMergedBy().GetLogin(),
+		BaseBranch: pr.GetBase().GetRef(),
+		HeadSHA:    pr.GetHead().GetSHA(),
+	}
+
+	// the latest review of every user counts
+	latest := make(map[string]*github.PullRequestReview)
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		rs, resp := internal.Must2(p.client.PullRequests.ListReviews(ctx, owner, repo, n, opts))
+		for _, r := range rs {
+			if s := r.GetState(); s == "APPROVED" || s == "CHANGES_REQUESTED" || s == "DISMISSED" {
+				latest[r.GetUser().GetLogin()] = r
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	res.Approvers, res.StaleApprovers = []string{}, []string{}
+	for u, r := range latest {
+		if r.GetState() != "APPROVED" || u == res.Author {
+			continue
+		}
+		res.Approvers = append(res.Approvers, u)
+		if r.GetCommitID() != res.HeadSHA {
+			res.StaleApprovers = append(res.StaleApprovers, u)
+		}
+	}
+	sort.Strings(res.Approvers)
+	sort.Strings(res.StaleApprovers)
+
+	res.RequiredChecks, res.ChecksPassed, res.ChecksUnknown = p.requiredChecks(owner, repo, res.BaseBranch, res.HeadSHA)
+	return res, pr.GetMergeCommitSHA()
+}
+
+// requiredChecks returns the state of all status checks of the commit, which
+// are required by the protection of the branch, and whether they passed. If
+// the required checks cannot be read, they are unknown and did not pass.
+func (p *RepoPlugin) requiredChecks(owner, repo, branch, sha string) ([]scm.StatusCheck, bool, bool) {
+	ctx := context.Background()
+	req, resp, err := p.client.Repositories.GetRequiredStatusChecks(ctx, owner, repo, branch)
+	if resp != nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound) {
+		// reading the branch protection requires administration permissions,
+		// and rulesets may require checks without any branch protection
+		log.Warn().Str("repo", owner+"/"+repo).Str("branch", branch).Int("status", resp.StatusCode).
+			Msg("Cannot read required status checks")
+		return []scm.StatusCheck{}, false, true
+	}
+	internal.MustNoErr(err)
+
+	states := make(map[string]string)
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		cs, resp := internal.Must2(p.client.Repositories.GetCombinedStatus(ctx, owner, repo, sha, opts))
+		for _, s := range cs.Statuses {
+			states[s.GetContext()] = s.GetState()
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	crOpts := &github.ListCheckRunsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		crs, resp := internal.Must2(p.client.Checks.ListCheckRunsForRef(ctx, owner, repo, sha, crOpts))
+		for _, cr := range crs.CheckRuns {
+			if cr.GetStatus() == "completed" {
+				states[cr.GetName()] = cr.GetConclusion()
+			} else {
+				states[cr.GetName()] = cr.GetStatus()
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		crOpts.Page = resp.NextPage
+	}
+
+	names := append([]string{}, req.Contexts...)
+	for _, c := range req.Checks {
+		names = append(names, c.Context)
+	}
+	checks, passed := make([]scm.StatusCheck, 0, len(names)), true
+	for _, n := range names {
+		s, ok := states[n]
+		if !ok {
+			s = "missing"
+		}
+		checks = append(checks, scm.StatusCheck{Name: n, State: s})
+		passed = passed && (s == "success" || s == "neutral" || s == "skipped")
+	}
+	return checks, passed, false
+}
+
+func prKey(repo string, n int) string {
+	return fmt.Sprintf("%s#%d", repo, n)
+}
+
+// ownerRepo returns the owner and name of a remote repository, e.g. "org" and
+// "zzz-web" for "https://github.com/org/zzz-web.git". Repositories on other
+// hosts than the GitHub instance are skipped.
+func (p *RepoPlugin) ownerRepo(url string) (string, string, bool) {
+	ep, err := transport.NewEndpoint(url)
+	if err != nil || ep.Protocol == "file" || !strings.EqualFold(ep.Host, p.host) {
+		return "", "", false
+	}
+	ps := strings.Split(strings.TrimSuffix(strings.Trim(ep.Path, "/"), ".git"), "/")
+	if len(ps) < 2 {
+		return "", "", false
+	}
+	return ps[len(ps)-2], ps[len(ps)-1], true
+}
@@ -0,0 +1,83 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package github
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gschauer/heimdall-dev/plugin/scm"
+)
+
+// newTestPlugin returns a plugin, whose client sends all requests to the
+// handler.
+func newTestPlugin(t *testing.T, h http.HandlerFunc) *RepoPlugin {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	setenv(t, map[string]string{"GITHUB_API_URL": srv.URL + "/api/v3"})
+	c, host, err := newAPIClient(http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &RepoPlugin{client: c, host: host}
+}
+
+func TestRequiredChecks(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		required string
+		checks   []scm.StatusCheck
+		passed   bool
+		unknown  bool
+	}{
+		{"passed", http.StatusOK, `{"contexts": ["ci/build"]}`,
+			[]scm.StatusCheck{{Name: "ci/build", State: "success"}}, true, false},
+		{"failed check run", http.StatusOK, `{"contexts": ["ci/build"], "checks": [{"context": "lint"}]}`,
+			[]scm.StatusCheck{{Name: "ci/build", State: "success"}, {Name: "lint", State: "failure"}}, false, false},
+		{"missing", http.StatusOK, `{"contexts": ["deploy"]}`,
+			[]scm.StatusCheck{{Name: "deploy", State: "missing"}}, false, false},
+		{"forbidden", http.StatusForbidden, `{"message": "Resource not accessible by integration"}`,
+			[]scm.StatusCheck{}, false, true},
+		{"not found", http.StatusNotFound, `{"message": "Branch not protected"}`,
+			[]scm.StatusCheck{}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPlugin(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v3/repos/org/zzz-web/branches/main/protection/required_status_checks":
+					w.WriteHeader(tt.status)
+					_, _ = w.Write([]byte(tt.required))
+				case "/api/v3/repos/org/zzz-web/commits/abc/status":
+					_, _ = w.Write([]byte(`{"statuses": [{"context": "ci/build", "state": "success"}]}`))
+				case "/api/v3/repos/org/zzz-web/commits/abc/check-runs":
+					_, _ = w.Write([]byte(`{"total_count": 1, "check_runs": [{"name": "lint", "status": "completed", "conclusion": "failure"}]}`))
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+
+			checks, passed, unknown := p.requiredChecks("org", "zzz-web", "main", "abc")
+			if !reflect.DeepEqual(checks, tt.checks) || passed != tt.passed || unknown != tt.unknown {
+				t.Errorf("requiredChecks() = %v, %v, %v, want %v, %v, %v", checks, passed, unknown, tt.checks, tt.passed, tt.unknown)
+			}
+		})
+	}
+}
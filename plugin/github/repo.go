@@ -16,22 +16,26 @@ package github
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/google/go-github/v49/github"
 	"github.com/gschauer/heimdall-dev/cfg"
 	"github.com/gschauer/heimdall-dev/internal"
 	"github.com/gschauer/heimdall-dev/plugin"
+	"github.com/gschauer/heimdall-dev/plugin/scm"
 	"github.com/gschauer/heimdall-dev/release"
 	"github.com/gschauer/heimdall-dev/res"
 	"github.com/rs/zerolog/log"
-	"golang.org/x/oauth2"
 )
 
 type RepoInfo struct {
 	ID            int64                                `json:"ID"`
 	Name          string                               `json:"name"`
+	FullName      string                               `json:"full_name"`
 	MasterBranch  string                               `json:"master_branch,omitempty"`
 	DefaultBranch string                               `json:"default_branch,omitempty"`
 	GitURL        string                               `json:"git_url,omitempty"`
@@ -41,61 +45,234 @@ type RepoInfo struct {
 }
 
 type RepoPlugin struct {
-	client    *github.Client
-	repoInfos []RepoInfo
+	client *github.Client
+	// host of the repositories, e.g. "github.com"
+	host        string
+	repoInfos   map[string]RepoInfo
+	protections []scm.BranchProtection
+	alerts      []Alert
+	scans       []AlertScan
+	runs        []WorkflowRun
+	ownership   []Ownership
+	prov        provenance
 }
 
+const (
+	reposFile       = "repos.json"
+	pullsFile       = "pulls.json"
+	protectionsFile = "protections.json"
+	alertsFile      = "alerts.json"
+	scansFile       = "scans.json"
+	runsFile        = "runs.json"
+	ownershipFile   = "ownership.json"
+)
+
 func (p *RepoPlugin) Load(o, n release.Info) {
+	p.repoInfos = make(map[string]RepoInfo)
 	for _, c := range n.Components {
-		c, _, _ = strings.Cut(c, "@")
-		c = strings.TrimSuffix(c, ".git")
-		ps := strings.Split(c, "/")
-		p.repoInfos = append(p.repoInfos, p.GetRepoInfo(ps[len(ps)-2], ps[len(ps)-1]))
+		url, _ := res.SplitRev(c)
+		url, _ = res.SplitPath(url)
+		owner, repo, ok := p.ownerRepo(url)
+		if !ok {
+			log.Debug().Str("url", url).Msg("Skipping non-GitHub repository")
+			continue
+		}
+		// components of a monorepo share the repository
+		if _, ok := p.repoInfos[owner+"/"+repo]; !ok {
+			p.repoInfos[owner+"/"+repo] = p.GetRepoInfo(owner, repo)
+		}
+	}
+	for _, rb := range scm.ReleaseBranches(n.Components, p.repoPath, p.branchExists, p.defaultBranch) {
+		owner, repo, _ := strings.Cut(rb.Repo, "/")
+		p.protections = append(p.protections, p.loadProtection(owner, repo, rb.Branch))
+		p.loadAlerts(owner, repo, rb.Branch)
 	}
+	for _, k := range internal.SortedKeys(p.repoInfos) {
+		owner, repo, _ := strings.Cut(k, "/")
+		p.loadRepoAlerts(owner, repo)
+	}
+	p.loadProvenance()
+	p.loadWorkflowRuns()
+	p.loadOwnership()
 }
 
 func (p *RepoPlugin) InitEnv(env map[string]any) {
-	env["github"] = map[string]any{"repos": res.ToMap(p.repoInfos[0])}
+	repos := make(map[string]any, len(p.repoInfos))
+	names := make([]string, 0, len(p.repoInfos))
+	for k := range p.repoInfos {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	allRepos := []any{}
+	for _, k := range names {
+		m := res.ToMap(p.repoInfos[k])
+		repos[k] = m
+		allRepos = append(allRepos, m)
+	}
+
+	prs := make(map[string]any)
+	var pulls []any
+	for _, pr := range p.prov.PullRequests {
+		m := res.ToMap(pr)
+		prs[prKey(pr.Repo, pr.Number)] = m
+		pulls = append(pulls, m)
+	}
+	protections := []any{}
+	for _, bp := range p.protections {
+		protections = append(protections, res.ToMap(bp))
+	}
+	alerts := []any{}
+	for _, a := range p.alerts {
+		alerts = append(alerts, res.ToMap(a))
+	}
+	scans := []any{}
+	for _, sc := range p.scans {
+		scans = append(scans, res.ToMap(sc))
+	}
+	runs := []any{}
+	for _, r := range p.runs {
+		runs = append(runs, res.ToMap(r))
+	}
+	ownership := []any{}
+	for _, o := range p.ownership {
+		ownership = append(ownership, res.ToMap(o))
+	}
+	var commits []any
+	for _, c := range p.prov.Commits {
+		m := res.ToMap(c)
+		l := []any{}
+		for _, n := range c.PullRequests {
+			l = append(l, prs[prKey(c.Repo, n)])
+		}
+		m["pullRequests"] = l
+		commits = append(commits, m)
+	}
+
+	env["github"] = map[string]any{
+		// repositories by "owner/name"
+		"repos":    repos,
+		"allRepos": allRepos,
+		// commits of the release with their merged pull requests
+		"commits":      commits,
+		"pullRequests": pulls,
+		// protection of the released branches
+		"branchProtections": protections,
+		// security alerts of the released branches and the default branches
+		"alerts": alerts,
+		// availability of the security alerts by repository and kind
+		"alertScans": scans,
+		// workflow runs of the evaluated commits
+		"workflowRuns": runs,
+		// code owners of the changed files and their approvals
+		"ownership": ownership,
+	}
+}
+
+// PullRequests returns the merged pull requests of every commit in the
+// release by commit hash.
+func (p *RepoPlugin) PullRequests() map[string][]*scm.PullRequest {
+	prs := make(map[string]*scm.PullRequest)
+	for _, pr := range p.prov.PullRequests {
+		prs[prKey(pr.Repo, pr.Number)] = pr
+	}
+	m := make(map[string][]*scm.PullRequest)
+	for _, c := range p.prov.Commits {
+		for _, n := range c.PullRequests {
+			m[c.Hash] = append(m[c.Hash], prs[prKey(c.Repo, n)])
+		}
+	}
+	return m
+}
+
+func (p *RepoPlugin) Save(dir string) error {
+	if err := plugin.SaveJSON(dir, reposFile, p.repoInfos); err != nil {
+		return err
+	}
+	if err := plugin.SaveJSON(dir, protectionsFile, p.protections); err != nil {
+		return err
+	}
+	if err := plugin.SaveJSON(dir, alertsFile, p.alerts); err != nil {
+		return err
+	}
+	if err := plugin.SaveJSON(dir, scansFile, p.scans); err != nil {
+		return err
+	}
+	if err := plugin.SaveJSON(dir, runsFile, p.runs); err != nil {
+		return err
+	}
+	if err := plugin.SaveJSON(dir, ownershipFile, p.ownership); err != nil {
+		return err
+	}
+	return plugin.SaveJSON(dir, pullsFile, p.prov)
+}
+
+func (p *RepoPlugin) Restore(dir string) error {
+	if err := plugin.LoadJSON(dir, reposFile, &p.repoInfos); err != nil {
+		return err
+	}
+	if err := plugin.LoadJSON(dir, protectionsFile, &p.protections); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := plugin.LoadJSON(dir, alertsFile, &p.alerts); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := plugin.LoadJSON(dir, scansFile, &p.scans); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := plugin.LoadJSON(dir, runsFile, &p.runs); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := plugin.LoadJSON(dir, ownershipFile, &p.ownership); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := plugin.LoadJSON(dir, pullsFile, &p.prov); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
 }
 
 func (p *RepoPlugin) GetRepoInfo(owner, repo string) RepoInfo {
 	r, _, err := p.client.Repositories.Get(context.Background(), owner, repo)
 	internal.MustNoErr(err)
 
-	prEnf, _, err := p.client.Repositories.GetPullRequestReviewEnforcement(context.Background(), owner, repo, r.GetDefaultBranch())
+	// unprotected branches have no review enforcement
+	prEnf, resp, err := p.client.Repositories.GetPullRequestReviewEnforcement(context.Background(), owner, repo, r.GetDefaultBranch())
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		prEnf, err = &github.PullRequestReviewsEnforcement{}, nil
+	}
 	internal.MustNoErr(err)
+	// only visible with admin permissions
+	sec := r.GetSecurityAndAnalysis()
+	if sec == nil {
+		sec = &github.SecurityAndAnalysis{}
+	}
 
 	return RepoInfo{
 		r.GetID(),
 		r.GetName(),
+		r.GetFullName(),
 		r.GetMasterBranch(),
 		r.GetDefaultBranch(),
 		r.GetGitURL(),
 		r.GetCloneURL(),
-		*r.GetSecurityAndAnalysis(),
+		*sec,
 		*prEnf,
 	}
 }
 
 func init() {
 	if !cfg.IsGitEnabled() {
+		plugin.RegisterOffline(&RepoPlugin{})
 		return
 	}
 
-	t := os.Getenv("GITHUB_TOKEN")
-	if t == "" {
-		log.Warn().Str("name", "GITHUB_TOKEN").Msg("undefined environment variable")
-		return
-	}
-	url := os.Getenv("GITHUB_API_URL")
-	if url == "" {
-		log.Warn().Str("name", "GITHUB_API_URL").Msg("undefined environment variable")
+	client, host, err := newClient()
+	if errors.Is(err, errNoCredentials) {
+		log.Warn().Err(err).Msg("undefined environment variable")
+		plugin.RegisterOffline(&RepoPlugin{})
 		return
 	}
+	internal.MustNoErr(err)
 
-	src := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: t})
-	httpClient := oauth2.NewClient(context.Background(), src)
-	client := internal.Must(github.NewEnterpriseClient(url+"/v3/", url+"/uploads/", httpClient))
-
-	plugin.Register(&RepoPlugin{client, nil})
+	plugin.Register(&RepoPlugin{client: client, host: host})
 }
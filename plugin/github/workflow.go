@@ -0,0 +1,187 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/plugin"
+	"github.com/gschauer/heimdall-dev/plugin/git"
+	"github.com/rs/zerolog/log"
+)
+
+// WorkflowRun is a GitHub Actions workflow run for the evaluated commit of a
+// component.
+type WorkflowRun struct {
+	Component string `json:"component"`
+	Repo      string `json:"repo"`
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	// Workflow is the file name of the workflow, e.g. "release.yml".
+	Workflow   string     `json:"workflow"`
+	Path       string     `json:"path"`
+	HeadSHA    string     `json:"headSha"`
+	HeadBranch string     `json:"headBranch"`
+	Event      string     `json:"event"`
+	Status     string     `json:"status"`
+	Conclusion string     `json:"conclusion"`
+	RunAttempt int        `json:"runAttempt"`
+	URL        string     `json:"url"`
+	Created    time.Time  `json:"created"`
+	Started    time.Time  `json:"started"`
+	Updated    time.Time  `json:"updated"`
+	Artifacts  []Artifact `json:"artifacts"`
+}
+
+// Artifact is an artifact uploaded by a workflow run.
+type Artifact struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// Digest is the SHA-256 digest of the artifact, e.g. "sha256:...", if
+	// provided by GitHub.
+	Digest  string `json:"digest"`
+	Expired bool   `json:"expired"`
+}
+
+// workflowRuns is the response of the workflow runs API. It is decoded
+// directly, since go-github lacks the head_sha filter and the path.
+type workflowRuns struct {
+	Runs []struct {
+		ID         int64     `json:"id"`
+		Name       string    `json:"name"`
+		Path       string    `json:"path"`
+		HeadSHA    string    `json:"head_sha"`
+		HeadBranch string    `json:"head_branch"`
+		Event      string    `json:"event"`
+		Status     string    `json:"status"`
+		Conclusion string    `json:"conclusion"`
+		RunAttempt int       `json:"run_attempt"`
+		HTMLURL    string    `json:"html_url"`
+		CreatedAt  time.Time `json:"created_at"`
+		StartedAt  time.Time `json:"run_started_at"`
+		UpdatedAt  time.Time `json:"updated_at"`
+	} `json:"workflow_runs"`
+}
+
+type artifacts struct {
+	Artifacts []struct {
+		Name        string `json:"name"`
+		SizeInBytes int64  `json:"size_in_bytes"`
+		Digest      string `json:"digest"`
+		Expired     bool   `json:"expired"`
+	} `json:"artifacts"`
+}
+
+// loadWorkflowRuns loads the workflow runs of the evaluated commit of every
+// component.
+func (p *RepoPlugin) loadWorkflowRuns() {
+	gp, ok := plugin.Find[*git.CommitPlugin]()
+	if !ok {
+		log.Warn().Msg("Skipping workflow runs without Git plugin")
+		return
+	}
+
+	cache := make(map[string][]WorkflowRun)
+	for name, c := range gp.Components() {
+		owner, repo, ok := p.ownerRepo(c.URL)
+		if !ok {
+			continue
+		}
+		k := owner + "/" + repo + "@" + c.Hash
+		if _, ok := cache[k]; !ok {
+			cache[k] = p.workflowRuns(owner, repo, c.Hash)
+		}
+		for _, r := range cache[k] {
+			r.Component = name
+			p.runs = append(p.runs, r)
+		}
+	}
+	sort.Slice(p.runs, func(i, j int) bool {
+		a, b := p.runs[i], p.runs[j]
+		return a.Component < b.Component || (a.Component == b.Component && a.ID < b.ID)
+	})
+}
+
+func (p *RepoPlugin) workflowRuns(owner, repo, sha string) []WorkflowRun {
+	log.Info().Str("repo", owner+"/"+repo).Str("hash", sha).Msg("Loading workflow runs")
+	runs := []WorkflowRun{}
+	for page := 1; ; page++ {
+		var rs workflowRuns
+		u := fmt.Sprintf("repos/%s/%s/actions/runs?head_sha=%s&per_page=100&page=%d", owner, repo, sha, page)
+		if !p.get(u, &rs) {
+			return runs
+		}
+		for _, r := range rs.Runs {
+			// dynamic workflows have a path like "dynamic/pages/pages-build-deployment"
+			wp, _, _ := strings.Cut(r.Path, "@")
+			runs = append(runs, WorkflowRun{
+				Repo:       owner + "/" + repo,
+				ID:         r.ID,
+				Name:       r.Name,
+				Workflow:   path.Base(wp),
+				Path:       wp,
+				HeadSHA:    r.HeadSHA,
+				HeadBranch: r.HeadBranch,
+				Event:      r.Event,
+				Status:     r.Status,
+				Conclusion: r.Conclusion,
+				RunAttempt: r.RunAttempt,
+				URL:        r.HTMLURL,
+				Created:    r.CreatedAt,
+				Started:    r.StartedAt,
+				Updated:    r.UpdatedAt,
+				Artifacts:  p.artifacts(owner, repo, r.ID),
+			})
+		}
+		if len(rs.Runs) < 100 {
+			return runs
+		}
+	}
+}
+
+func (p *RepoPlugin) artifacts(owner, repo string, run int64) []Artifact {
+	as := []Artifact{}
+	for page := 1; ; page++ {
+		var ls artifacts
+		if !p.get(fmt.Sprintf("repos/%s/%s/actions/runs/%d/artifacts?per_page=100&page=%d", owner, repo, run, page), &ls) {
+			return as
+		}
+		for _, a := range ls.Artifacts {
+			as = append(as, Artifact{a.Name, a.SizeInBytes, a.Digest, a.Expired})
+		}
+		if len(ls.Artifacts) < 100 {
+			return as
+		}
+	}
+}
+
+// get decodes the response of a GET request into v. It returns false, if the
+// resource does not exist, e.g. if Actions are disabled.
+func (p *RepoPlugin) get(u string, v any) bool {
+	req := internal.Must(p.client.NewRequest(http.MethodGet, u, nil))
+	resp, err := p.client.Do(context.Background(), req, v)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false
+	}
+	internal.MustNoErr(err)
+	return true
+}
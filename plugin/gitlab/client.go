@@ -0,0 +1,159 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gschauer/heimdall-dev/cfg"
+	"github.com/rs/zerolog/log"
+)
+
+// client is a minimal client of the GitLab REST API v4, which authenticates
+// by a personal, group or project access token.
+type client struct {
+	base  *url.URL
+	token string
+	hc    *http.Client
+}
+
+// Error is returned for responses with an unexpected status code.
+type Error struct {
+	URL        string
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("GET %s: %d %s", e.URL, e.StatusCode, e.Message)
+}
+
+// newClient creates a client for the GitLab instance at baseURL, e.g.
+// "https://gitlab.local".
+func newClient(baseURL, token string) (*client, error) {
+	u, err := url.Parse(strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/api/v4"))
+	if err != nil {
+		return nil, err
+	}
+	return &client{base: u.JoinPath("api", "v4"), token: token, hc: http.DefaultClient}, nil
+}
+
+// host returns the host of the repositories.
+func (c *client) host() string {
+	return c.base.Hostname()
+}
+
+// get decodes the response of the resource at the escaped path into v. It returns false,
+// if the resource does not exist. Requests exceeding the rate limit are
+// retried after the limit is reset.
+func (c *client) get(path string, q url.Values, v any) (bool, error) {
+	_, ok, err := c.do(path, q, v)
+	return ok, err
+}
+
+func (c *client) do(path string, q url.Values, v any) (http.Header, bool, error) {
+	// path is escaped, since project IDs contain escaped slashes, e.g. "group%2Fproject"
+	u := c.base.String() + "/" + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	for i := 0; ; i++ {
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return nil, false, err
+		}
+		req.Header.Set("PRIVATE-TOKEN", c.token)
+		resp, err := c.hc.Do(req)
+		if err != nil {
+			return nil, false, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, false, err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return resp.Header, true, json.Unmarshal(body, v)
+		case resp.StatusCode == http.StatusNotFound:
+			return resp.Header, false, nil
+		case resp.StatusCode == http.StatusTooManyRequests && i < cfg.GetGitLabRetries():
+			wait := rateLimitWait(resp.Header)
+			log.Warn().Str("URL", req.URL.Path).Dur("wait", wait).Msg("GitLab rate limit exceeded")
+			time.Sleep(wait)
+		default:
+			var e struct {
+				Message any `json:"message"`
+				Error   any `json:"error"`
+			}
+			_ = json.Unmarshal(body, &e)
+			msg := e.Message
+			if msg == nil {
+				msg = e.Error
+			}
+			if msg == nil {
+				msg = http.StatusText(resp.StatusCode)
+			}
+			return resp.Header, false, &Error{req.URL.Path, resp.StatusCode, fmt.Sprint(msg)}
+		}
+	}
+}
+
+// list returns all pages of the collection at path.
+func list[T any](c *client, path string, q url.Values) ([]T, bool, error) {
+	if q == nil {
+		q = url.Values{}
+	}
+	q.Set("per_page", "100")
+	ts := []T{}
+	for page := "1"; page != ""; {
+		q.Set("page", page)
+		var l []T
+		h, ok, err := c.do(path, q, &l)
+		if !ok || err != nil {
+			return ts, ok, err
+		}
+		ts = append(ts, l...)
+		page = h.Get("X-Next-Page")
+	}
+	return ts, true, nil
+}
+
+// rateLimitWait returns the duration until the rate limit is reset.
+func rateLimitWait(h http.Header) time.Duration {
+	if n, err := strconv.Atoi(h.Get("Retry-After")); err == nil {
+		return time.Duration(n) * time.Second
+	}
+	if reset, err := strconv.ParseInt(h.Get("RateLimit-Reset"), 10, 64); err == nil {
+		if d := time.Until(time.Unix(reset, 0)); d > 0 && d < cfg.GetGitLabMaxRateLimitWait() {
+			return d + time.Second
+		}
+	}
+	return time.Minute
+}
+
+// projectID returns the URL-encoded path of a project, which can be used
+// instead of its numeric ID.
+func projectID(path string) string {
+	return url.PathEscape(path)
+}
@@ -0,0 +1,158 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package gitlab
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// serve returns a client of a local GitLab instance, which responds to
+// requests authorized by the token "glpat".
+func serve(t *testing.T, h http.HandlerFunc) *client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("PRIVATE-TOKEN"); got != "glpat" {
+			t.Errorf("PRIVATE-TOKEN = %q, want glpat", got)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	c, err := newClient(srv.URL+"/api/v4/", "glpat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClientList(t *testing.T) {
+	c := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/group%2Fweb/protected_branches" {
+			t.Errorf("unexpected request %s", r.URL.EscapedPath())
+		}
+		if got := r.URL.Query().Get("per_page"); got != "100" {
+			t.Errorf("per_page = %q, want 100", got)
+		}
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Header().Set("X-Next-Page", "2")
+			_, _ = w.Write([]byte(`[{"name": "main"}, {"name": "release/*"}]`))
+		case "2":
+			w.Header().Set("X-Next-Page", "")
+			_, _ = w.Write([]byte(`[{"name": "hotfix/*"}]`))
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	})
+
+	pbs, ok, err := list[protectedBranch](c, "projects/"+projectID("group/web")+"/protected_branches", nil)
+	if err != nil || !ok {
+		t.Fatalf("list() = %v, %v", ok, err)
+	}
+	var names []string
+	for _, pb := range pbs {
+		names = append(names, pb.Name)
+	}
+	if want := []string{"main", "release/*", "hotfix/*"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+}
+
+func TestClientNotFound(t *testing.T) {
+	c := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "404 Project Not Found"}`))
+	})
+
+	var v struct{}
+	if ok, err := c.get("projects/"+projectID("group/gone"), nil, &v); ok || err != nil {
+		t.Errorf("get() = %v, %v, want false, nil", ok, err)
+	}
+	ls, ok, err := list[protectedBranch](c, "projects/"+projectID("group/gone")+"/protected_branches", nil)
+	if ok || err != nil || len(ls) != 0 {
+		t.Errorf("list() = %v, %v, %v, want [], false, nil", ls, ok, err)
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	calls := 0
+	c := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": 42}`))
+	})
+
+	var v struct {
+		ID int `json:"id"`
+	}
+	ok, err := c.get("projects/"+projectID("group/web"), nil, &v)
+	if !ok || err != nil {
+		t.Fatalf("get() = %v, %v", ok, err)
+	}
+	if v.ID != 42 || calls != 2 {
+		t.Errorf("id = %d after %d calls, want 42 after 2", v.ID, calls)
+	}
+}
+
+func TestClientError(t *testing.T) {
+	c := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message": "403 Forbidden"}`))
+	})
+
+	var v struct{}
+	_, err := c.get("projects/"+projectID("group/web")+"/approvals", nil, &v)
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("err = %v, want *Error", err)
+	}
+	if e.StatusCode != http.StatusForbidden || e.Message != "403 Forbidden" || e.URL != "/api/v4/projects/group/web/approvals" {
+		t.Errorf("err = %+v", e)
+	}
+}
+
+func TestProjectPath(t *testing.T) {
+	c, err := newClient("https://gitlab.local", "glpat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &ProjectPlugin{client: c}
+	tests := []struct {
+		url, want string
+		ok        bool
+	}{
+		{"https://gitlab.local/group/web.git", "group/web", true},
+		{"https://GitLab.local/group/subgroup/web", "group/subgroup/web", true},
+		{"ssh://git@gitlab.local:2222/group/web.git", "group/web", true},
+		{"git@gitlab.local:group/subgroup/web.git", "group/subgroup/web", true},
+		{"https://gitlab.local/web.git", "web", false},
+		{"https://github.com/org/web.git", "", false},
+		{"/tmp/group/web.git", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := p.projectPath(tt.url)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("projectPath(%q) = %q, %v, want %q, %v", tt.url, got, ok, tt.want, tt.ok)
+		}
+	}
+}
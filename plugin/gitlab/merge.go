@@ -0,0 +1,169 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package gitlab
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/plugin"
+	"github.com/gschauer/heimdall-dev/plugin/git"
+	"github.com/gschauer/heimdall-dev/plugin/scm"
+	"github.com/rs/zerolog/log"
+)
+
+// MergeRequest contains the provenance facts of a merged merge request. The
+// IID is its Number and the target branch its BaseBranch. The head pipeline
+// is the only required check.
+type MergeRequest struct {
+	scm.PullRequest
+	ApprovalsRequired int `json:"approvalsRequired"`
+	// Approved is set, if the approval rules of the merge request are met.
+	Approved bool `json:"approved"`
+}
+
+// provenance contains the merge requests of all commits in the release range.
+type provenance struct {
+	Commits      []scm.CommitProvenance `json:"commits"`
+	PullRequests []*MergeRequest        `json:"pullRequests"`
+}
+
+type user struct {
+	Username string `json:"username"`
+}
+
+// loadProvenance maps the commits of the Git plugin to their merge requests.
+func (p *ProjectPlugin) loadProvenance() {
+	gp, ok := plugin.Find[*git.CommitPlugin]()
+	if !ok {
+		log.Warn().Msg("Skipping merge requests without Git plugin")
+		return
+	}
+
+	mrs := make(map[string]*MergeRequest)
+	for name, c := range gp.Components() {
+		p.loadCommits(name, c.URL, c.Commits, mrs)
+		for _, sm := range c.Submodules {
+			p.loadCommits(name, sm.URL, sm.Commits, mrs)
+		}
+	}
+	for _, mr := range mrs {
+		p.prov.PullRequests = append(p.prov.PullRequests, mr)
+	}
+	sort.Slice(p.prov.PullRequests, func(i, j int) bool {
+		a, b := p.prov.PullRequests[i], p.prov.PullRequests[j]
+		return a.Repo < b.Repo || (a.Repo == b.Repo && a.Number < b.Number)
+	})
+	sort.SliceStable(p.prov.Commits, func(i, j int) bool { return p.prov.Commits[i].Component < p.prov.Commits[j].Component })
+}
+
+func (p *ProjectPlugin) loadCommits(comp, url string, cs []git.Commit, mrs map[string]*MergeRequest) {
+	project, ok := p.projectPath(url)
+	if !ok {
+		return
+	}
+	for _, c := range cs {
+		cp := scm.CommitProvenance{Hash: c.Hash, Component: comp, Repo: project, PullRequests: []int{}}
+		type mergeRequest struct {
+			IID   int    `json:"iid"`
+			State string `json:"state"`
+		}
+		ls, _ := internal.Must2(list[mergeRequest](p.client, "projects/"+projectID(project)+"/repository/commits/"+c.Hash+"/merge_requests", nil))
+		for _, mr := range ls {
+			if mr.State != "merged" {
+				continue
+			}
+			key := prKey(project, mr.IID)
+			if mrs[key] == nil {
+				mrs[key] = p.loadMergeRequest(project, mr.IID)
+			}
+			cp.PullRequests = append(cp.PullRequests, mr.IID)
+		}
+		p.prov.Commits = append(p.prov.Commits, cp)
+	}
+}
+
+func (p *ProjectPlugin) loadMergeRequest(project string, iid int) *MergeRequest {
+	log.Debug().Str("project", project).Int("iid", iid).Msg("Loading merge request")
+	base := fmt.Sprintf("projects/%s/merge_requests/%d", projectID(project), iid)
+	var mr struct {
+		WebURL         string `json:"web_url"`
+		Title          string `json:"title"`
+		Author         user   `json:"author"`
+		MergeUser      *user  `json:"merge_user"`
+		MergedBy       *user  `json:"merged_by"`
+		TargetBranch   string `json:"target_branch"`
+		SHA            string `json:"sha"`
+		Squash         bool   `json:"squash"`
+		MergeCommitSHA string `json:"merge_commit_sha"`
+		HeadPipeline   *struct {
+			Status string `json:"status"`
+		} `json:"head_pipeline"`
+	}
+	internal.Must(p.client.get(base, nil, &mr))
+
+	res := &MergeRequest{PullRequest: scm.PullRequest{
+		Repo:           project,
+		Number:         iid,
+		URL:            mr.WebURL,
+		Title:          mr.Title,
+		Author:         mr.Author.Username,
+		BaseBranch:     mr.TargetBranch,
+		HeadSHA:        mr.SHA,
+		Approvers:      []string{},
+		StaleApprovers: []string{},
+		RequiredChecks: []scm.StatusCheck{},
+	}}
+	if mr.MergeUser != nil {
+		res.MergedBy = mr.MergeUser.Username
+	} else if mr.MergedBy != nil {
+		res.MergedBy = mr.MergedBy.Username
+	}
+	if mr.HeadPipeline != nil {
+		res.RequiredChecks = append(res.RequiredChecks, scm.StatusCheck{Name: "pipeline", State: mr.HeadPipeline.Status})
+		res.ChecksPassed = mr.HeadPipeline.Status == "success"
+	}
+	switch {
+	case mr.Squash:
+		res.MergeMethod = scm.Squash
+	case mr.MergeCommitSHA != "":
+		res.MergeMethod = scm.Merge
+	default:
+		// fast-forward merges have no merge commit
+		res.MergeMethod = scm.Rebase
+	}
+
+	var ap struct {
+		Approved          bool `json:"approved"`
+		ApprovalsRequired int  `json:"approvals_required"`
+		ApprovedBy        []struct {
+			User user `json:"user"`
+		} `json:"approved_by"`
+	}
+	internal.Must(p.client.get(base+"/approvals", nil, &ap))
+	for _, a := range ap.ApprovedBy {
+		if a.User.Username != res.Author {
+			res.Approvers = append(res.Approvers, a.User.Username)
+		}
+	}
+	sort.Strings(res.Approvers)
+	res.ApprovalsRequired, res.Approved = ap.ApprovalsRequired, ap.Approved
+	return res
+}
+
+func prKey(project string, iid int) string {
+	return fmt.Sprintf("%s!%d", project, iid)
+}
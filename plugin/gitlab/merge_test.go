@@ -0,0 +1,83 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package gitlab
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gschauer/heimdall-dev/plugin/scm"
+)
+
+func TestLoadMergeRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		mr        string
+		approvals string
+		want      MergeRequest
+	}{
+		{
+			name: "approved",
+			mr: `{"web_url": "https://gitlab.local/group/web/-/merge_requests/7", "title": "Add login",
+				"author": {"username": "alice"}, "merge_user": {"username": "carol"}, "target_branch": "main",
+				"sha": "abc", "merge_commit_sha": "def", "head_pipeline": {"status": "success"}}`,
+			approvals: `{"approved": true, "approvals_required": 2,
+				"approved_by": [{"user": {"username": "dave"}}, {"user": {"username": "alice"}}, {"user": {"username": "bob"}}]}`,
+			want: MergeRequest{scm.PullRequest{Repo: "group/web", Number: 7, URL: "https://gitlab.local/group/web/-/merge_requests/7",
+				Title: "Add login", Author: "alice", MergedBy: "carol", BaseBranch: "main", HeadSHA: "abc",
+				Approvers: []string{"bob", "dave"}, StaleApprovers: []string{},
+				RequiredChecks: []scm.StatusCheck{{Name: "pipeline", State: "success"}}, ChecksPassed: true,
+				MergeMethod: scm.Merge}, 2, true},
+		},
+		{
+			name: "unapproved",
+			mr: `{"author": {"username": "alice"}, "merged_by": {"username": "alice"}, "target_branch": "main",
+				"sha": "abc", "squash": true, "head_pipeline": {"status": "failed"}}`,
+			approvals: `{"approved": false, "approvals_required": 1, "approved_by": []}`,
+			want: MergeRequest{scm.PullRequest{Repo: "group/web", Number: 7, Author: "alice", MergedBy: "alice", BaseBranch: "main",
+				HeadSHA: "abc", Approvers: []string{}, StaleApprovers: []string{},
+				RequiredChecks: []scm.StatusCheck{{Name: "pipeline", State: "failed"}}, MergeMethod: scm.Squash}, 1, false},
+		},
+		{
+			name:      "fast-forward",
+			mr:        `{"author": {"username": "alice"}, "sha": "abc"}`,
+			approvals: `{"approved": true, "approved_by": [{"user": {"username": "alice"}}]}`,
+			want: MergeRequest{scm.PullRequest{Repo: "group/web", Number: 7, Author: "alice", HeadSHA: "abc",
+				Approvers: []string{}, StaleApprovers: []string{}, RequiredChecks: []scm.StatusCheck{},
+				MergeMethod: scm.Rebase}, 0, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := serve(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.EscapedPath() {
+				case "/api/v4/projects/group%2Fweb/merge_requests/7":
+					_, _ = w.Write([]byte(tt.mr))
+				case "/api/v4/projects/group%2Fweb/merge_requests/7/approvals":
+					_, _ = w.Write([]byte(tt.approvals))
+				default:
+					t.Errorf("unexpected request %s", r.URL.EscapedPath())
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+
+			p := &ProjectPlugin{client: c}
+			if got := p.loadMergeRequest("group/web", 7); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("loadMergeRequest() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
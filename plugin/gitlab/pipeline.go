@@ -0,0 +1,140 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package gitlab
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/plugin"
+	"github.com/gschauer/heimdall-dev/plugin/git"
+	"github.com/rs/zerolog/log"
+)
+
+// Pipeline is a CI pipeline for the evaluated commit of a component.
+type Pipeline struct {
+	Component string `json:"component"`
+	Project   string `json:"project"`
+	ID        int    `json:"id"`
+	SHA       string `json:"sha"`
+	Ref       string `json:"ref"`
+	// Source is the trigger of the pipeline, e.g. "push" or "merge_request_event".
+	Source  string    `json:"source"`
+	Status  string    `json:"status"`
+	URL     string    `json:"url"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// Vulnerability is a vulnerability of the default branch of a project, which
+// is detected or confirmed. Dismissed and resolved vulnerabilities are
+// omitted.
+type Vulnerability struct {
+	Project string `json:"project"`
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	// Kind is the type of the scanner, e.g. "sast", "dependency_scanning" or
+	// "secret_detection".
+	Kind     string    `json:"kind"`
+	Severity string    `json:"severity"`
+	State    string    `json:"state"`
+	Created  time.Time `json:"created"`
+	// AgeDays is the age of the vulnerability in days, when the facts were
+	// loaded.
+	AgeDays int `json:"ageDays"`
+}
+
+// loadPipelines loads the pipelines of the evaluated commit of every
+// component.
+func (p *ProjectPlugin) loadPipelines() {
+	gp, ok := plugin.Find[*git.CommitPlugin]()
+	if !ok {
+		log.Warn().Msg("Skipping pipelines without Git plugin")
+		return
+	}
+
+	type pipeline struct {
+		ID        int       `json:"id"`
+		SHA       string    `json:"sha"`
+		Ref       string    `json:"ref"`
+		Source    string    `json:"source"`
+		Status    string    `json:"status"`
+		WebURL    string    `json:"web_url"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+	for name, c := range gp.Components() {
+		project, ok := p.projectPath(c.URL)
+		if !ok {
+			continue
+		}
+		log.Info().Str("project", project).Str("hash", c.Hash).Msg("Loading pipelines")
+		ls, _ := internal.Must2(list[pipeline](p.client, "projects/"+projectID(project)+"/pipelines", url.Values{"sha": {c.Hash}}))
+		for _, pl := range ls {
+			p.pipelines = append(p.pipelines, Pipeline{name, project, pl.ID, pl.SHA, pl.Ref, pl.Source, pl.Status, pl.WebURL, pl.CreatedAt, pl.UpdatedAt})
+		}
+	}
+	sort.Slice(p.pipelines, func(i, j int) bool {
+		a, b := p.pipelines[i], p.pipelines[j]
+		return a.Component < b.Component || (a.Component == b.Component && a.ID < b.ID)
+	})
+}
+
+// loadVulnerabilities loads the vulnerabilities of all projects. They are
+// only available in the Ultimate tier, otherwise they are skipped.
+func (p *ProjectPlugin) loadVulnerabilities() {
+	type vulnerability struct {
+		ID         int       `json:"id"`
+		Title      string    `json:"title"`
+		ReportType string    `json:"report_type"`
+		Severity   string    `json:"severity"`
+		State      string    `json:"state"`
+		CreatedAt  time.Time `json:"created_at"`
+	}
+	paths := make([]string, 0, len(p.projects))
+	for path := range p.projects {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		ls, ok, err := list[vulnerability](p.client, "projects/"+projectID(path)+"/vulnerabilities", nil)
+		var e *Error
+		if !ok && (err == nil || (errors.As(err, &e) && e.StatusCode == http.StatusForbidden)) {
+			log.Warn().Str("project", path).Msg("Skipping unavailable vulnerabilities")
+			continue
+		}
+		internal.MustNoErr(err)
+		for _, v := range ls {
+			if v.State != "detected" && v.State != "confirmed" {
+				continue
+			}
+			p.vulnerabilities = append(p.vulnerabilities, Vulnerability{
+				Project:  path,
+				ID:       v.ID,
+				Title:    v.Title,
+				Kind:     v.ReportType,
+				Severity: v.Severity,
+				State:    v.State,
+				Created:  v.CreatedAt,
+				AgeDays:  int(time.Since(v.CreatedAt).Hours() / 24),
+			})
+		}
+	}
+}
@@ -0,0 +1,246 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package gitlab
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/gschauer/heimdall-dev/plugin"
+	"github.com/gschauer/heimdall-dev/plugin/scm"
+	"github.com/gschauer/heimdall-dev/release"
+	"github.com/gschauer/heimdall-dev/res"
+	"github.com/gschauer/heimdall-dev/secret"
+	"github.com/rs/zerolog/log"
+)
+
+// ProjectInfo contains the settings of a GitLab project.
+type ProjectInfo struct {
+	ID            int    `json:"id"`
+	Path          string `json:"path"`
+	DefaultBranch string `json:"defaultBranch"`
+	Visibility    string `json:"visibility"`
+	MergeMethod   string `json:"mergeMethod"`
+	// PipelineMustSucceed only allows merging merge requests with a
+	// successful pipeline.
+	PipelineMustSucceed  bool `json:"pipelineMustSucceed"`
+	DiscussionsResolved  bool `json:"discussionsResolved"`
+	ApprovalsBeforeMerge int  `json:"approvalsBeforeMerge"`
+	// ResetApprovalsOnPush removes approvals, if new commits are pushed.
+	ResetApprovalsOnPush bool `json:"resetApprovalsOnPush"`
+	AuthorApproval       bool `json:"authorApproval"`
+	CommitterApproval    bool `json:"committerApproval"`
+}
+
+// ProjectPlugin loads the facts of the GitLab projects of all components,
+// which are hosted on the GitLab instance GITLAB_URL.
+type ProjectPlugin struct {
+	client          *client
+	projects        map[string]ProjectInfo
+	protections     []BranchProtection
+	prov            provenance
+	pipelines       []Pipeline
+	vulnerabilities []Vulnerability
+}
+
+const (
+	projectsFile        = "projects.json"
+	protectionsFile     = "protections.json"
+	mergesFile          = "merges.json"
+	pipelinesFile       = "pipelines.json"
+	vulnerabilitiesFile = "vulnerabilities.json"
+)
+
+func (p *ProjectPlugin) Load(o, n release.Info) {
+	p.projects = make(map[string]ProjectInfo)
+	for _, c := range n.Components {
+		u, _ := res.SplitRev(c)
+		u, _ = res.SplitPath(u)
+		path, ok := p.projectPath(u)
+		if !ok {
+			log.Debug().Str("url", u).Msg("Skipping non-GitLab repository")
+			continue
+		}
+		// components of a monorepo share the project
+		if _, ok := p.projects[path]; !ok {
+			p.projects[path] = p.loadProject(path)
+		}
+	}
+	for _, rb := range scm.ReleaseBranches(n.Components, p.projectPath, p.branchExists, p.defaultBranch) {
+		p.protections = append(p.protections, p.loadProtection(rb.Repo, rb.Branch))
+	}
+	p.loadProvenance()
+	p.loadPipelines()
+	p.loadVulnerabilities()
+}
+
+func (p *ProjectPlugin) InitEnv(env map[string]any) {
+	paths := make([]string, 0, len(p.projects))
+	for k := range p.projects {
+		paths = append(paths, k)
+	}
+	sort.Strings(paths)
+	projects, allProjects := make(map[string]any), []any{}
+	for _, k := range paths {
+		m := res.ToMap(p.projects[k])
+		projects[k] = m
+		allProjects = append(allProjects, m)
+	}
+
+	prs := make(map[string]any)
+	pulls := []any{}
+	for _, pr := range p.prov.PullRequests {
+		m := res.ToMap(pr)
+		prs[prKey(pr.Repo, pr.Number)] = m
+		pulls = append(pulls, m)
+	}
+	commits := []any{}
+	for _, c := range p.prov.Commits {
+		m := res.ToMap(c)
+		l := []any{}
+		for _, n := range c.PullRequests {
+			l = append(l, prs[prKey(c.Repo, n)])
+		}
+		m["pullRequests"] = l
+		commits = append(commits, m)
+	}
+
+	env["gitlab"] = map[string]any{
+		// projects by path, e.g. "group/subgroup/project"
+		"projects":    projects,
+		"allProjects": allProjects,
+		// protection of the released branches
+		"branchProtections": toMaps(p.protections),
+		// commits of the release with their merge requests
+		"commits":      commits,
+		"pullRequests": pulls,
+		// pipelines of the evaluated commits
+		"pipelines": toMaps(p.pipelines),
+		// vulnerabilities of the default branches
+		"vulnerabilities": toMaps(p.vulnerabilities),
+	}
+}
+
+func (p *ProjectPlugin) Save(dir string) error {
+	for f, v := range map[string]any{
+		projectsFile:        p.projects,
+		protectionsFile:     p.protections,
+		mergesFile:          p.prov,
+		pipelinesFile:       p.pipelines,
+		vulnerabilitiesFile: p.vulnerabilities,
+	} {
+		if err := plugin.SaveJSON(dir, f, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *ProjectPlugin) Restore(dir string) error {
+	for f, v := range map[string]any{
+		projectsFile:        &p.projects,
+		protectionsFile:     &p.protections,
+		mergesFile:          &p.prov,
+		pipelinesFile:       &p.pipelines,
+		vulnerabilitiesFile: &p.vulnerabilities,
+	} {
+		if err := plugin.LoadJSON(dir, f, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *ProjectPlugin) loadProject(path string) ProjectInfo {
+	log.Info().Str("project", path).Msg("Loading GitLab project")
+	var pr struct {
+		ID                                        int    `json:"id"`
+		PathWithNamespace                         string `json:"path_with_namespace"`
+		DefaultBranch                             string `json:"default_branch"`
+		Visibility                                string `json:"visibility"`
+		MergeMethod                               string `json:"merge_method"`
+		OnlyAllowMergeIfPipelineSucceeds          bool   `json:"only_allow_merge_if_pipeline_succeeds"`
+		OnlyAllowMergeIfAllDiscussionsAreResolved bool   `json:"only_allow_merge_if_all_discussions_are_resolved"`
+	}
+	ok := internal.Must(p.client.get("projects/"+projectID(path), nil, &pr))
+	internal.MustOkMsgf(pr, ok, "GitLab project %s not found", path)
+
+	// approval settings are only available in the Premium tier
+	var ap struct {
+		ApprovalsBeforeMerge                      int  `json:"approvals_before_merge"`
+		ResetApprovalsOnPush                      bool `json:"reset_approvals_on_push"`
+		MergeRequestsAuthorApproval               bool `json:"merge_requests_author_approval"`
+		MergeRequestsDisableCommittersApproval    bool `json:"merge_requests_disable_committers_approval"`
+		DisableOverridingApproversPerMergeRequest bool `json:"disable_overriding_approvers_per_merge_request"`
+	}
+	internal.Must(p.client.get("projects/"+projectID(path)+"/approvals", nil, &ap))
+
+	return ProjectInfo{
+		ID:                   pr.ID,
+		Path:                 pr.PathWithNamespace,
+		DefaultBranch:        pr.DefaultBranch,
+		Visibility:           pr.Visibility,
+		MergeMethod:          pr.MergeMethod,
+		PipelineMustSucceed:  pr.OnlyAllowMergeIfPipelineSucceeds,
+		DiscussionsResolved:  pr.OnlyAllowMergeIfAllDiscussionsAreResolved,
+		ApprovalsBeforeMerge: ap.ApprovalsBeforeMerge,
+		ResetApprovalsOnPush: ap.ResetApprovalsOnPush,
+		AuthorApproval:       ap.MergeRequestsAuthorApproval,
+		CommitterApproval:    !ap.MergeRequestsDisableCommittersApproval,
+	}
+}
+
+// projectPath returns the path of a project including its groups, e.g.
+// "group/subgroup/project" for "https://gitlab.local/group/subgroup/project.git".
+// Repositories on other hosts than the GitLab instance are skipped.
+func (p *ProjectPlugin) projectPath(url string) (string, bool) {
+	ep, err := transport.NewEndpoint(url)
+	if err != nil || ep.Protocol == "file" || !strings.EqualFold(ep.Host, p.client.host()) {
+		return "", false
+	}
+	path := strings.TrimSuffix(strings.Trim(ep.Path, "/"), ".git")
+	return path, strings.Contains(path, "/")
+}
+
+func toMaps[T any](ts []T) []any {
+	l := []any{}
+	for _, t := range ts {
+		l = append(l, res.ToMap(t))
+	}
+	return l
+}
+
+func init() {
+	baseURL := os.Getenv("GITLAB_URL")
+	if baseURL == "" {
+		log.Warn().Str("name", "GITLAB_URL").Msg("undefined environment variable")
+		plugin.RegisterOffline(&ProjectPlugin{})
+		return
+	}
+
+	token := secret.Getenv("GITLAB_TOKEN")
+	if token == "" {
+		log.Warn().Str("name", "GITLAB_TOKEN").Msg("undefined environment variable")
+		plugin.RegisterOffline(&ProjectPlugin{})
+		return
+	}
+
+	c, err := newClient(baseURL, token)
+	internal.MustNoErr(err)
+	plugin.Register(&ProjectPlugin{client: c})
+}
@@ -0,0 +1,103 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package gitlab
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gschauer/heimdall-dev/internal"
+	"github.com/rs/zerolog/log"
+)
+
+// BranchProtection is the protection of a released branch. Unprotected
+// branches have Protected unset.
+type BranchProtection struct {
+	Project string `json:"project"`
+	Branch  string `json:"branch"`
+	// Rule is the name of the protected branch, which may contain wildcards,
+	// e.g. "release/*".
+	Rule      string `json:"rule"`
+	Protected bool   `json:"protected"`
+	// PushAccess and MergeAccess are the roles, users and groups allowed to
+	// push and merge, e.g. "Maintainers" or "No one".
+	PushAccess        []string `json:"pushAccess"`
+	MergeAccess       []string `json:"mergeAccess"`
+	ForcePushAllowed  bool     `json:"forcePushAllowed"`
+	CodeOwnerApproval bool     `json:"codeOwnerApproval"`
+}
+
+// branchExists reports whether the branch exists in the project.
+func (p *ProjectPlugin) branchExists(project, branch string) bool {
+	var b struct{}
+	return internal.Must(p.client.get("projects/"+projectID(project)+"/repository/branches/"+url.PathEscape(branch), nil, &b))
+}
+
+func (p *ProjectPlugin) defaultBranch(project string) string {
+	return p.projects[project].DefaultBranch
+}
+
+type accessLevel struct {
+	Description string `json:"access_level_description"`
+}
+
+type protectedBranch struct {
+	Name                      string        `json:"name"`
+	PushAccessLevels          []accessLevel `json:"push_access_levels"`
+	MergeAccessLevels         []accessLevel `json:"merge_access_levels"`
+	AllowForcePush            bool          `json:"allow_force_push"`
+	CodeOwnerApprovalRequired bool          `json:"code_owner_approval_required"`
+}
+
+// loadProtection loads the protection of a branch. Protected branches may
+// contain wildcards, and an exact match takes precedence.
+func (p *ProjectPlugin) loadProtection(project, branch string) BranchProtection {
+	log.Info().Str("project", project).Str("branch", branch).Msg("Loading branch protection")
+	bp := BranchProtection{Project: project, Branch: branch, PushAccess: []string{}, MergeAccess: []string{}, ForcePushAllowed: true}
+	pbs, _ := internal.Must2(list[protectedBranch](p.client, "projects/"+projectID(project)+"/protected_branches", nil))
+
+	var match *protectedBranch
+	for i, pb := range pbs {
+		if pb.Name == branch {
+			match = &pbs[i]
+			break
+		} else if match == nil && wildcardMatch(pb.Name, branch) {
+			match = &pbs[i]
+		}
+	}
+	if match == nil {
+		return bp
+	}
+
+	bp.Rule, bp.Protected = match.Name, true
+	for _, a := range match.PushAccessLevels {
+		bp.PushAccess = append(bp.PushAccess, a.Description)
+	}
+	for _, a := range match.MergeAccessLevels {
+		bp.MergeAccess = append(bp.MergeAccess, a.Description)
+	}
+	bp.ForcePushAllowed = match.AllowForcePush
+	bp.CodeOwnerApproval = match.CodeOwnerApprovalRequired
+	return bp
+}
+
+// wildcardMatch reports whether the branch matches the name of a protected
+// branch. Unlike path.Match, "*" also matches "/", e.g. "release/*" matches
+// "release/1.x/fix".
+func wildcardMatch(name, branch string) bool {
+	re := "^" + strings.ReplaceAll(regexp.QuoteMeta(name), `\*`, ".*") + "$"
+	return regexp.MustCompile(re).MatchString(branch)
+}
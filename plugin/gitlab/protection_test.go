@@ -0,0 +1,74 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package gitlab
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestWildcardMatch(t *testing.T) {
+	tests := []struct {
+		name, branch string
+		want         bool
+	}{
+		{"main", "main", true},
+		{"main", "main2", false},
+		{"release/*", "release/1.x", true},
+		{"release/*", "release/1.x/fix", true},
+		{"release/*", "release", false},
+		{"*-stable", "1.4-stable", true},
+		{"*-stable", "team/1.4-stable", true},
+		{"v1.?", "v1.x", false},
+		{"v1.[0-9]", "v1.4", false},
+		{"v1.[0-9]", "v1.[0-9]", true},
+	}
+	for _, tt := range tests {
+		if got := wildcardMatch(tt.name, tt.branch); got != tt.want {
+			t.Errorf("wildcardMatch(%q, %q) = %v, want %v", tt.name, tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestLoadProtection(t *testing.T) {
+	c := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[
+			{"name": "release/*", "push_access_levels": [{"access_level_description": "Maintainers"}],
+				"merge_access_levels": [{"access_level_description": "Developers + Maintainers"}], "allow_force_push": true},
+			{"name": "release/1.x/hotfix", "push_access_levels": [{"access_level_description": "No one"}],
+				"merge_access_levels": [{"access_level_description": "Maintainers"}], "code_owner_approval_required": true}
+		]`))
+	})
+	p := &ProjectPlugin{client: c}
+
+	want := BranchProtection{Project: "group/web", Branch: "release/1.x/fix", Rule: "release/*", Protected: true,
+		PushAccess: []string{"Maintainers"}, MergeAccess: []string{"Developers + Maintainers"}, ForcePushAllowed: true}
+	if got := p.loadProtection("group/web", "release/1.x/fix"); !reflect.DeepEqual(got, want) {
+		t.Errorf("loadProtection() = %+v, want %+v", got, want)
+	}
+
+	// an exact match takes precedence over wildcards
+	want = BranchProtection{Project: "group/web", Branch: "release/1.x/hotfix", Rule: "release/1.x/hotfix", Protected: true,
+		PushAccess: []string{"No one"}, MergeAccess: []string{"Maintainers"}, CodeOwnerApproval: true}
+	if got := p.loadProtection("group/web", "release/1.x/hotfix"); !reflect.DeepEqual(got, want) {
+		t.Errorf("loadProtection() = %+v, want %+v", got, want)
+	}
+
+	want = BranchProtection{Project: "group/web", Branch: "main", PushAccess: []string{}, MergeAccess: []string{}, ForcePushAllowed: true}
+	if got := p.loadProtection("group/web", "main"); !reflect.DeepEqual(got, want) {
+		t.Errorf("loadProtection() = %+v, want %+v", got, want)
+	}
+}
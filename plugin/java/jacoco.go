@@ -44,20 +44,31 @@ type CovRec struct {
 }
 
 type JaCoCoPlugin struct {
-	rel release.Info
+	total CovRec
 }
 
+const jacocoFile = "jacoco.json"
+
 func (p *JaCoCoPlugin) Load(o, n release.Info) {
-	p.rel = n
+	var crs []CovRec
+	for _, c := range n.Components {
+		name, _ := res.CompRev(c)
+		tag := n.Release
+		crs = append(crs, LoadCovCSV(filepath.Join(cfg.GetArtifactRepoBase(), name, tag, "reports", "jacoco", "test", "jacocoTestReport.csv"))...)
+	}
+	p.total = Aggregate(crs...)
 }
 
 func (p *JaCoCoPlugin) InitEnv(env map[string]any) {
-	for _, c := range p.rel.Components {
-		n, _ := res.CompRev(c)
-		tag := p.rel.Release
-		crs := LoadCovCSV(filepath.Join(cfg.GetArtifactRepoBase(), n, tag, "reports", "jacoco", "test", "jacocoTestReport.csv"))
-		env["jacoco"] = res.ToMap(Aggregate(crs...))
-	}
+	env["jacoco"] = res.ToMap(p.total)
+}
+
+func (p *JaCoCoPlugin) Save(dir string) error {
+	return plugin.SaveJSON(dir, jacocoFile, p.total)
+}
+
+func (p *JaCoCoPlugin) Restore(dir string) error {
+	return plugin.LoadJSON(dir, jacocoFile, &p.total)
 }
 
 func LoadCovCSV(uri string) (crs []CovRec) {
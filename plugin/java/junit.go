@@ -28,18 +28,29 @@ import (
 )
 
 type JUnitPlugin struct {
-	suite *junit.Suite
+	totals junit.Totals
 }
 
+const junitFile = "junit.json"
+
 func (p *JUnitPlugin) Load(o, n release.Info) {
 	c, _ := res.CompRev(n.Components[0])
 	path := filepath.Join(cfg.GetArtifactRepoBase(), c, n.Release, "test-results", "test")
-	p.suite = &junit.Suite{Suites: loadSuites(path)}
-	p.suite.Aggregate()
+	suite := &junit.Suite{Suites: loadSuites(path)}
+	suite.Aggregate()
+	p.totals = suite.Totals
 }
 
 func (p *JUnitPlugin) InitEnv(env map[string]any) {
-	env["junit"] = res.ToMap(p.suite.Totals)
+	env["junit"] = res.ToMap(p.totals)
+}
+
+func (p *JUnitPlugin) Save(dir string) error {
+	return plugin.SaveJSON(dir, junitFile, p.totals)
+}
+
+func (p *JUnitPlugin) Restore(dir string) error {
+	return plugin.LoadJSON(dir, junitFile, &p.totals)
 }
 
 // loadSuites recursively loads all files in the given directory, recursively.
@@ -15,65 +15,69 @@
 package jira
 
 import (
-	"encoding/json"
 	"os"
-	"path/filepath"
-	"time"
+	"strings"
 
 	"github.com/andygrunwald/go-jira"
-	"github.com/gschauer/heimdall-dev/cfg"
-	"github.com/gschauer/heimdall-dev/internal"
 	"github.com/gschauer/heimdall-dev/plugin"
 	"github.com/gschauer/heimdall-dev/release"
-	"github.com/gschauer/heimdall-dev/res"
+	"github.com/gschauer/heimdall-dev/secret"
 	"github.com/rs/zerolog/log"
 )
 
 type IssuePlugin struct {
 	client *jira.Client
-	rel    *release.Info
+	record IssueRecord
 }
 
+const issuesFile = "issues.json"
+
 func (p *IssuePlugin) Load(o, n release.Info) {
-	p.rel = &n
-	is := ListIssues(p.client, os.Args[1])
-	j := internal.Must(json.Marshal(is))
+	jql := "fixVersion = " + quote(n.Release)
+	p.record = IssueRecord{jql, ListIssues(p.client, jql)}
+}
 
-	d := filepath.Join(cfg.GetArtifactRepoBase(), "jira", n.Release, "issues", time.Now().Format("2006-01-02T15:04:05"), "issues.json")
-	internal.MustNoErr(os.MkdirAll(filepath.Dir(d), 0700))
-	internal.MustNoErr(os.WriteFile(d, j, 0600))
+// quote returns s as a JQL string literal.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
 }
 
 func (p *IssuePlugin) InitEnv(env map[string]any) {
-	d := filepath.Join(cfg.GetArtifactRepoBase(), "jira", p.rel.Release, "issues")
-	dirs := internal.Must(os.ReadDir(d))
-	date := dirs[0].Name() // TODO: pick correct date
-
-	f := internal.Must(res.Open(filepath.Join(d, date, "issues.json")))
-	defer func() { _ = f.Close() }()
-
-	var ir IssueRecord
-	internal.MustNoErr(json.NewDecoder(f).Decode(&ir))
 	env["jira"] = map[string]any{
-		"issues": ir.Issues,
+		"issues": p.record.Issues,
 	}
 }
 
+// Issues returns the issues of the release.
+func (p *IssuePlugin) Issues() []Issue {
+	return p.record.Issues
+}
+
+func (p *IssuePlugin) Save(dir string) error {
+	return plugin.SaveJSON(dir, issuesFile, p.record)
+}
+
+func (p *IssuePlugin) Restore(dir string) error {
+	return plugin.LoadJSON(dir, issuesFile, &p.record)
+}
+
 func init() {
 	baseURL := os.Getenv("JIRA_BASE_URL")
 	if baseURL == "" {
 		log.Warn().Str("name", "JIRA_BASE_URL").Msg("undefined environment variable")
+		plugin.RegisterOffline(&IssuePlugin{})
 		return
 	}
 
-	token := os.Getenv("JIRA_TOKEN")
+	token := secret.Getenv("JIRA_TOKEN")
 	if token == "" {
 		log.Warn().Str("name", "JIRA_TOKEN").Msg("undefined environment variable")
+		plugin.RegisterOffline(&IssuePlugin{})
 		return
 	}
 
 	client, _ := NewClient(baseURL, token)
-	p := IssuePlugin{client, nil}
+	p := IssuePlugin{client: client}
 	plugin.Register(&p)
 }
 
@@ -82,6 +86,11 @@ type Issue struct {
 	Type    string `json:"type"`
 	Summary string `json:"summary"`
 	Status  string `json:"status"`
+	URL     string `json:"url,omitempty"`
+	// Assignee is the user name of the assignee.
+	Assignee string `json:"assignee,omitempty"`
+	// Transitioner is the user, who changed the issue to its current status.
+	Transitioner string `json:"transitioner,omitempty"`
 }
 
 func (i Issue) String() string {
@@ -101,10 +110,47 @@ func NewClient(url, token string) (*jira.Client, error) {
 
 // ListIssues searches for Jira issues matching the given JQL query.
 func ListIssues(client *jira.Client, jql string) []Issue {
-	is, _, _ := client.Issue.Search(jql, nil)
+	is, _, _ := client.Issue.Search(jql, &jira.SearchOptions{Expand: "changelog"})
+	base := client.GetBaseURL()
 	r := make([]Issue, len(is))
 	for k, i := range is {
-		r[k] = Issue{i.Key, i.Fields.Type.Name, i.Fields.Summary, i.Fields.Status.Name}
+		r[k] = Issue{
+			Key:          i.Key,
+			Type:         i.Fields.Type.Name,
+			Summary:      i.Fields.Summary,
+			Status:       i.Fields.Status.Name,
+			URL:          base.JoinPath("browse", i.Key).String(),
+			Assignee:     userName(i.Fields.Assignee),
+			Transitioner: transitioner(i),
+		}
 	}
 	return r
 }
+
+// transitioner returns the author of the last status change of the issue.
+func transitioner(i jira.Issue) string {
+	if i.Changelog == nil {
+		return ""
+	}
+	name := ""
+	for _, h := range i.Changelog.Histories {
+		for _, it := range h.Items {
+			if it.Field == "status" {
+				author := h.Author
+				name = userName(&author)
+			}
+		}
+	}
+	return name
+}
+
+// userName returns the name of Jira Server users or the account ID of Jira
+// Cloud users.
+func userName(u *jira.User) string {
+	if u == nil {
+		return ""
+	} else if u.Name != "" {
+		return u.Name
+	}
+	return u.AccountID
+}
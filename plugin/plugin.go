@@ -15,6 +15,7 @@
 package plugin
 
 import (
+	"encoding/json"
 	"reflect"
 
 	"github.com/gschauer/heimdall-dev/release"
@@ -23,12 +24,86 @@ import (
 
 var Registry []Plugin
 
+// Offline contains plugins, which are not configured for loading facts, but
+// can still restore facts from a snapshot.
+var Offline []Plugin
+
 type Plugin interface {
 	Load(o, n release.Info)
 	InitEnv(env map[string]any)
 }
 
 func Register(p Plugin) {
-	log.Info().Str("plugin", reflect.TypeOf(p).Elem().String()).Msg("Registering")
+	log.Info().Str("plugin", Name(p)).Msg("Registering")
 	Registry = append(Registry, p)
 }
+
+// RegisterOffline registers a plugin, which is only used for re-evaluating
+// facts from a snapshot.
+func RegisterOffline(p Plugin) {
+	log.Info().Str("plugin", Name(p)).Msg("Registering offline")
+	Offline = append(Offline, p)
+}
+
+// Name returns the qualified type name of the plugin, e.g. "jira.IssuePlugin".
+func Name(p Plugin) string {
+	return reflect.TypeOf(p).Elem().String()
+}
+
+// Facts returns a copy of the environment without functions, i.e., the facts
+// which can be serialized.
+func Facts(env map[string]any) map[string]any {
+	m := make(map[string]any, len(env))
+	for k, v := range env {
+		if vm, ok := v.(map[string]any); ok {
+			m[k] = Facts(vm)
+		} else if v == nil || reflect.TypeOf(v).Kind() != reflect.Func {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// NumericFacts flattens the facts of the environment into a map of numeric
+// values. Nested keys are joined with ".", and lists are represented by their
+// length, e.g. "jira.issues.count".
+func NumericFacts(env map[string]any) map[string]float64 {
+	var m map[string]any
+	bs, err := json.Marshal(Facts(env))
+	if err != nil || json.Unmarshal(bs, &m) != nil {
+		return nil
+	}
+
+	fs := make(map[string]float64)
+	flatten(fs, "", m)
+	return fs
+}
+
+func flatten(fs map[string]float64, prefix string, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if prefix != "" {
+				k = prefix + "." + k
+			}
+			flatten(fs, k, e)
+		}
+	case []any:
+		fs[prefix+".count"] = float64(len(v))
+	case float64:
+		fs[prefix] = v
+	}
+}
+
+// Find returns the registered plugin of type T, e.g. to access the facts of
+// another plugin. Offline plugins are only considered, if T is not
+// registered, so that their facts can be accessed after restoring a snapshot.
+func Find[T Plugin]() (T, bool) {
+	for _, p := range append(append([]Plugin{}, Registry...), Offline...) {
+		if t, ok := p.(T); ok {
+			return t, true
+		}
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,46 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package plugin
+
+import (
+	"github.com/gschauer/heimdall-dev/release"
+	"github.com/rs/zerolog/log"
+)
+
+// Publisher is implemented by plugins, which act on the outcome of the gate,
+// e.g. by tagging the evaluated commits.
+type Publisher interface {
+	Publish(r release.Report, opts PublishOptions) error
+}
+
+type PublishOptions struct {
+	// Digest is the SHA-256 digest of the JSON report.
+	Digest string
+	// DryRun only logs the actions, which would be performed.
+	DryRun bool
+}
+
+// Publish passes the report to all plugins, which implement Publisher.
+func Publish(ps []Plugin, r release.Report, opts PublishOptions) error {
+	for _, p := range ps {
+		if pub, ok := p.(Publisher); ok {
+			log.Info().Str("plugin", Name(p)).Bool("dryRun", opts.DryRun).Msg("Publishing")
+			if err := pub.Publish(r, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package scm
+
+import (
+	"path"
+	"sort"
+
+	"github.com/gschauer/heimdall-dev/cfg"
+	"github.com/gschauer/heimdall-dev/plugin"
+	"github.com/gschauer/heimdall-dev/plugin/git"
+	"github.com/gschauer/heimdall-dev/res"
+)
+
+// RepoBranch is the released branch of a repository.
+type RepoBranch struct {
+	// Repo is the path of the repository on its host, e.g. "org/repo".
+	Repo   string
+	Branch string
+}
+
+// ReleaseBranches returns the distinct released branches of all components,
+// whose repository has a path on the host. exists reports whether the branch
+// exists in the repository.
+func ReleaseBranches(comps []string, repoPath func(url string) (string, bool),
+	exists func(repo, branch string) bool, defaultBranch func(repo string) string) []RepoBranch {
+	gp, _ := plugin.Find[*git.CommitPlugin]()
+	var rbs []RepoBranch
+	seen := make(map[RepoBranch]bool)
+	for _, c := range comps {
+		u, rev := res.SplitRev(c)
+		u, _ = res.SplitPath(u)
+		repo, ok := repoPath(u)
+		if !ok {
+			continue
+		}
+		var branches []string
+		if gp != nil {
+			name, _ := res.CompRev(c)
+			if gc := gp.Components()[name]; gc != nil {
+				branches = gc.ContainingBranches
+			}
+		}
+
+		rb := RepoBranch{repo, ReleaseBranch(rev, branches, defaultBranch(repo), func(b string) bool { return exists(repo, b) })}
+		if !seen[rb] {
+			seen[rb] = true
+			rbs = append(rbs, rb)
+		}
+	}
+	sort.Slice(rbs, func(i, j int) bool {
+		a, b := rbs[i], rbs[j]
+		return a.Repo < b.Repo || (a.Repo == b.Repo && a.Branch < b.Branch)
+	})
+	return rbs
+}
+
+// ReleaseBranch returns the branch being released, i.e. rev if it is a
+// branch, otherwise the first containing branch matching
+// cfg.GetReleaseBranches and finally the default branch.
+func ReleaseBranch(rev string, containing []string, defaultBranch string, exists func(branch string) bool) string {
+	if rev != "" && exists(rev) {
+		return rev
+	}
+	for _, pat := range cfg.GetReleaseBranches() {
+		for _, b := range containing {
+			if ok, _ := path.Match(pat, b); ok {
+				return b
+			}
+		}
+	}
+	return defaultBranch
+}
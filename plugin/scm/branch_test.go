@@ -0,0 +1,64 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package scm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReleaseBranch(t *testing.T) {
+	exists := func(b string) bool { return b == "release/1.x" || b == "develop" }
+	tests := []struct {
+		name       string
+		rev        string
+		containing []string
+		want       string
+	}{
+		{"branch", "develop", []string{"main"}, "develop"},
+		{"tag", "v1.4", []string{"feature/x", "release/1.x", "main"}, "release/1.x"},
+		{"pattern order", "v1.4", []string{"main", "hotfix/1.4.1"}, "hotfix/1.4.1"},
+		{"nested branch", "v1.4", []string{"release/1/x"}, "trunk"},
+		{"default branch", "", nil, "trunk"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReleaseBranch(tt.rev, tt.containing, "trunk", exists); got != tt.want {
+				t.Errorf("ReleaseBranch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseBranches(t *testing.T) {
+	comps := []string{
+		"https://code.local/org/web.git@develop",
+		"https://code.local/org/mono.git//services/api@v1.4",
+		"https://code.local/org/mono.git//services/db@v1.4",
+		"https://other.local/org/lib.git@main",
+	}
+	repoPath := func(u string) (string, bool) {
+		p := strings.TrimPrefix(u, "https://code.local/")
+		return strings.TrimSuffix(p, ".git"), p != u
+	}
+	exists := func(repo, b string) bool { return repo == "org/web" && b == "develop" }
+	defaultBranch := func(repo string) string { return "main" }
+
+	want := []RepoBranch{{"org/mono", "main"}, {"org/web", "develop"}}
+	if got := ReleaseBranches(comps, repoPath, exists, defaultBranch); !reflect.DeepEqual(got, want) {
+		t.Errorf("ReleaseBranches() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,90 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Package scm contains the facts of source code hosts, which do not depend on
+// the host, so that the same policies apply to every host.
+package scm
+
+// Merge methods
+const (
+	Merge  = "merge"
+	Squash = "squash"
+	Rebase = "rebase"
+)
+
+// BranchProtection is the effective protection of a released branch. On
+// GitHub, it combines the classic branch protection and the repository
+// rulesets. If both apply, the stricter setting wins.
+type BranchProtection struct {
+	Repo   string `json:"repo"`
+	Branch string `json:"branch"`
+	// Protected is set, if a branch protection or any ruleset applies.
+	Protected           bool     `json:"protected"`
+	RequiredReviews     int      `json:"requiredReviews"`
+	DismissStaleReviews bool     `json:"dismissStaleReviews"`
+	CodeOwnerReviews    bool     `json:"codeOwnerReviews"`
+	LastPushApproval    bool     `json:"lastPushApproval"`
+	RequiredChecks      []string `json:"requiredChecks"`
+	// StrictChecks requires branches to be up-to-date before merging.
+	StrictChecks     bool `json:"strictChecks"`
+	SignedCommits    bool `json:"signedCommits"`
+	LinearHistory    bool `json:"linearHistory"`
+	ForcePushAllowed bool `json:"forcePushAllowed"`
+	DeletionAllowed  bool `json:"deletionAllowed"`
+	// EnforceAdmins applies the branch protection to administrators as well.
+	EnforceAdmins bool `json:"enforceAdmins"`
+	// Rulesets are the names of the active rulesets for the branch.
+	Rulesets []string `json:"rulesets"`
+	// Unknown is set, if the classic branch protection cannot be read, e.g.
+	// by a token without administration permissions.
+	Unknown bool `json:"unknown"`
+}
+
+// PullRequest contains the provenance facts of a merged pull request.
+type PullRequest struct {
+	Repo       string `json:"repo"`
+	Number     int    `json:"number"`
+	URL        string `json:"url"`
+	Title      string `json:"title"`
+	Author     string `json:"author"`
+	MergedBy   string `json:"mergedBy"`
+	BaseBranch string `json:"baseBranch"`
+	HeadSHA    string `json:"headSha"`
+	// Approvers are the distinct users, whose latest review approved the pull request.
+	Approvers []string `json:"approvers"`
+	// StaleApprovers approved a commit, which is not the head of the pull request.
+	StaleApprovers []string      `json:"staleApprovers"`
+	RequiredChecks []StatusCheck `json:"requiredChecks"`
+	// ChecksPassed is true, if all required checks of the head commit succeeded.
+	ChecksPassed bool `json:"checksPassed"`
+	// ChecksUnknown is set, if the required checks cannot be read, e.g. by a
+	// token without administration permissions. ChecksPassed is false then.
+	ChecksUnknown bool   `json:"checksUnknown"`
+	MergeMethod   string `json:"mergeMethod"`
+}
+
+// StatusCheck is the state of a commit status or the conclusion of a check run.
+type StatusCheck struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// CommitProvenance maps a commit of the release to its pull requests.
+type CommitProvenance struct {
+	Hash      string `json:"hash"`
+	Component string `json:"component"`
+	Repo      string `json:"repo"`
+	// PullRequests are the numbers of the merged pull requests in Repo.
+	PullRequests []int `json:"pullRequests"`
+}
@@ -0,0 +1,110 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package plugin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/gschauer/heimdall-dev/release"
+	"github.com/gschauer/heimdall-dev/secret"
+	"github.com/rs/zerolog/log"
+)
+
+// Snapshotter is implemented by plugins, which can save the facts loaded by
+// Load and restore them later without network access.
+// Each plugin saves its facts into its own directory.
+type Snapshotter interface {
+	Save(dir string) error
+	Restore(dir string) error
+}
+
+const releasesFile = "releases.json"
+
+type releases struct {
+	Old release.Info `json:"old"`
+	New release.Info `json:"new"`
+}
+
+// SaveSnapshot saves the releases and the facts of all registered plugins
+// into dir.
+func SaveSnapshot(dir string, o, n release.Info) error {
+	if err := SaveJSON(dir, releasesFile, releases{o, n}); err != nil {
+		return err
+	}
+
+	for _, p := range Registry {
+		if s, ok := p.(Snapshotter); ok {
+			d := filepath.Join(dir, Name(p))
+			if err := os.MkdirAll(d, 0700); err != nil {
+				return err
+			}
+			if err := s.Save(d); err != nil {
+				return err
+			}
+		}
+	}
+	log.Info().Str("dir", dir).Msg("Saved facts snapshot")
+	return nil
+}
+
+// RestoreSnapshot restores the releases and the facts of all plugins, which
+// are contained in the snapshot. It returns the restored plugins.
+func RestoreSnapshot(dir string) (o, n release.Info, ps []Plugin, err error) {
+	var rs releases
+	if err = LoadJSON(dir, releasesFile, &rs); err != nil {
+		return
+	}
+
+	for _, p := range append(append([]Plugin{}, Registry...), Offline...) {
+		d := filepath.Join(dir, Name(p))
+		s, ok := p.(Snapshotter)
+		if !ok {
+			continue
+		} else if _, err = os.Stat(d); err != nil {
+			log.Warn().Str("plugin", Name(p)).Msg("No facts in snapshot")
+			continue
+		}
+
+		if err = s.Restore(d); err != nil {
+			return
+		}
+		ps = append(ps, p)
+	}
+	return rs.Old, rs.New, ps, nil
+}
+
+// SaveJSON writes v as JSON to the file name in dir.
+// Registered secrets are redacted.
+func SaveJSON(dir, name string, v any) error {
+	bs, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), []byte(secret.Redact(string(bs))), 0600)
+}
+
+// LoadJSON reads the JSON file name in dir into v.
+func LoadJSON(dir, name string, v any) error {
+	bs, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bs, v)
+}
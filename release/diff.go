@@ -0,0 +1,90 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package release
+
+import "github.com/gschauer/heimdall-dev/internal"
+
+// Delta describes the changes between the reports of two releases.
+type Delta struct {
+	Old     Info          `json:"old"`
+	New     Info          `json:"new"`
+	Verdict StatusChange  `json:"verdict"`
+	Changed []CheckChange `json:"changed"`
+	Added   []CheckChange `json:"added"`
+	Removed []CheckChange `json:"removed"`
+	Facts   []FactChange  `json:"facts"`
+}
+
+type StatusChange struct {
+	Old Status `json:"old"`
+	New Status `json:"new"`
+}
+
+// CheckChange is the change of a step. A step consists of all checks with the
+// same name, and its status is the worst status of these checks.
+type CheckChange struct {
+	Name string `json:"name"`
+	StatusChange
+}
+
+type FactChange struct {
+	Name string  `json:"name"`
+	Old  float64 `json:"old"`
+	New  float64 `json:"new"`
+}
+
+func (f FactChange) Delta() float64 {
+	return f.New - f.Old
+}
+
+// Diff compares the reports of two releases. It returns the steps, whose
+// status changed, new and removed steps as well as numeric facts that moved.
+func Diff(o, n Report) (d Delta) {
+	d.Old, d.New = o.New, n.New
+	d.Verdict = StatusChange{o.Verdict, n.Verdict}
+
+	oldSteps, newSteps := steps(o.Checks), steps(n.Checks)
+	for _, name := range internal.SortedKeys(newSteps) {
+		if s, ok := oldSteps[name]; !ok {
+			d.Added = append(d.Added, CheckChange{name, StatusChange{New: newSteps[name]}})
+		} else if s != newSteps[name] {
+			d.Changed = append(d.Changed, CheckChange{name, StatusChange{s, newSteps[name]}})
+		}
+	}
+	for _, name := range internal.SortedKeys(oldSteps) {
+		if _, ok := newSteps[name]; !ok {
+			d.Removed = append(d.Removed, CheckChange{name, StatusChange{Old: oldSteps[name]}})
+		}
+	}
+
+	for _, name := range internal.SortedKeys(n.Facts) {
+		if v, ok := o.Facts[name]; ok && v != n.Facts[name] {
+			d.Facts = append(d.Facts, FactChange{name, v, n.Facts[name]})
+		}
+	}
+	return
+}
+
+func steps(cs []Check) map[string]Status {
+	m := make(map[string]Status)
+	for _, c := range cs {
+		if s, ok := m[c.Name]; !ok || severity[c.Status] > severity[s] {
+			m[c.Name] = c.Status
+		}
+	}
+	return m
+}
+
+var severity = map[Status]int{OK: 0, Warn: 1, Failed: 2}
@@ -0,0 +1,80 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package release
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	o := Report{
+		New:     Info{Name: "ZZZ", Release: "1.3"},
+		Verdict: Warn,
+		Checks: []Check{
+			{Name: "Coverage", Status: OK},
+			{Name: "Tests", Status: OK},
+			{Name: "Tests", Status: Warn},
+			{Name: "Stories", Status: Failed},
+			{Name: "Signed", Status: OK},
+		},
+		Facts: map[string]float64{"junit.tests": 10, "jacoco.line_covered": 70, "jira.issues": 3},
+	}
+	n := Report{
+		New:     Info{Name: "ZZZ", Release: "1.4"},
+		Verdict: Failed,
+		Checks: []Check{
+			{Name: "Coverage", Status: OK},
+			{Name: "Tests", Status: Failed},
+			{Name: "Tests", Status: OK},
+			{Name: "Stories", Status: Failed},
+			{Name: "Licenses", Status: Warn},
+		},
+		Facts: map[string]float64{"junit.tests": 12, "jacoco.line_covered": 70, "jacoco.line_missed": 5},
+	}
+
+	want := Delta{
+		Old:     o.New,
+		New:     n.New,
+		Verdict: StatusChange{Warn, Failed},
+		// the status of a step is the worst status of its checks
+		Changed: []CheckChange{{"Tests", StatusChange{Warn, Failed}}},
+		Added:   []CheckChange{{"Licenses", StatusChange{New: Warn}}},
+		Removed: []CheckChange{{"Signed", StatusChange{Old: OK}}},
+		// facts only present in one report did not move
+		Facts: []FactChange{{"junit.tests", 10, 12}},
+	}
+	if got := Diff(o, n); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+	if d := want.Facts[0].Delta(); d != 2 {
+		t.Errorf("Delta() = %v, want 2", d)
+	}
+}
+
+func TestDiffUnchanged(t *testing.T) {
+	r := Report{
+		Verdict: OK,
+		Checks:  []Check{{Name: "Tests", Status: OK}},
+		Facts:   map[string]float64{"junit.tests": 10},
+	}
+	d := Diff(r, r)
+	if d.Changed != nil || d.Added != nil || d.Removed != nil || d.Facts != nil {
+		t.Errorf("Diff() = %+v, want no changes", d)
+	}
+	if d.Verdict != (StatusChange{OK, OK}) {
+		t.Errorf("Verdict = %+v", d.Verdict)
+	}
+}
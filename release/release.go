@@ -14,6 +14,11 @@
 
 package release
 
+import (
+	"strconv"
+	"strings"
+)
+
 type Info struct {
 	Name       string   `json:"name" yaml:"name"`
 	Release    string   `json:"release" yaml:"release"`
@@ -23,3 +28,45 @@ type Info struct {
 func (i Info) String() string {
 	return i.Name + " " + i.Release
 }
+
+// Major returns the major version of the release, e.g. "1" for "1.4".
+func (i Info) Major() string {
+	m, _, _ := strings.Cut(i.Release, ".")
+	return m
+}
+
+// IsMajor checks whether the new release increments the major version.
+func IsMajor(o, n Info) bool {
+	return o.Major() != n.Major()
+}
+
+// CompareVersions compares two releases by their dot-separated segments, e.g.
+// "1.10" > "1.9". Numeric segments are compared as numbers, others as strings.
+// The result is -1, 0 or +1.
+func CompareVersions(a, b string) int {
+	as, bs := strings.FieldsFunc(a, isSep), strings.FieldsFunc(b, isSep)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, errX := strconv.Atoi(as[i])
+		y, errY := strconv.Atoi(bs[i])
+		switch {
+		case errX == nil && errY == nil && x != y:
+			if x < y {
+				return -1
+			}
+			return 1
+		case (errX != nil || errY != nil) && as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+func isSep(r rune) bool {
+	return r == '.' || r == '-' || r == '+'
+}
@@ -0,0 +1,42 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package release
+
+import "time"
+
+// Report is the outcome of evaluating all checks against a release.
+type Report struct {
+	Product string    `json:"product"`
+	Old     Info      `json:"old"`
+	New     Info      `json:"new"`
+	Version string    `json:"heimdall_version"`
+	Date    time.Time `json:"date"`
+	Verdict Status    `json:"verdict"`
+	Checks  []Check   `json:"checks"`
+	// Facts contains the numeric facts of all plugins, e.g. "jacoco.line_covered".
+	Facts map[string]float64 `json:"facts,omitempty"`
+	// Trends contains the values of facts across the last releases.
+	Trends []Trend `json:"trends,omitempty"`
+}
+
+type Trend struct {
+	Name   string       `json:"name"`
+	Points []TrendPoint `json:"points"`
+}
+
+type TrendPoint struct {
+	Release string  `json:"release"`
+	Value   float64 `json:"value"`
+}
@@ -20,10 +20,11 @@ import (
 )
 
 type Check struct {
-	Name      string
-	Status    Status
-	Reference string
-	Comment   string
+	Name      string `json:"name"`
+	Type      string `json:"type,omitempty"`
+	Status    Status `json:"status"`
+	Reference string `json:"reference"`
+	Comment   string `json:"comment"`
 }
 
 type Status string
@@ -34,6 +35,9 @@ const (
 	Failed Status = "Failed"
 )
 
+// Optional is the type of checks, which do not fail the verdict.
+const Optional = "optional"
+
 func ToStatus(ok any) Status {
 	v := reflect.ValueOf(ok)
 	fmt.Println("STATUS", ok, v.Kind(), v.IsZero())
@@ -42,3 +46,18 @@ func ToStatus(ok any) Status {
 	}
 	return Failed
 }
+
+// Verdict returns the overall status of all checks. Failed optional checks
+// only result in a warning.
+func Verdict(cs []Check) Status {
+	v := OK
+	for _, c := range cs {
+		switch {
+		case c.Status == Failed && c.Type != Optional:
+			return Failed
+		case c.Status != OK:
+			v = Warn
+		}
+	}
+	return v
+}
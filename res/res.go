@@ -44,12 +44,51 @@ func Open(uri string) (io.ReadCloser, error) {
 	return os.Open(uri)
 }
 
+// CompRev returns the name and the revision of a component, e.g. "zzz-web"
+// and "1.3" for "https://code.local/org/zzz-web.git@1.3".
+// Components within a repository are named after their path, e.g. "api" for
+// "https://code.local/org/mono.git//services/api@1.3".
 func CompRev(uri string) (string, string) {
-	n, rev, _ := strings.Cut(uri, "@")
-	n = path.Base(n)
+	n, rev := SplitRev(uri)
+	if _, p := SplitPath(n); p != "" {
+		return path.Base(p), rev
+	}
+	n = path.Base(strings.TrimSuffix(n, "/"))
 	return strings.TrimSuffix(n, ".git"), rev
 }
 
+// SplitPath splits a URL into the URL of the repository and the path of the
+// component within the repository, which is separated by "//", e.g.
+// "https://code.local/org/mono.git" and "services/api" for
+// "https://code.local/org/mono.git//services/api".
+func SplitPath(url string) (string, string) {
+	start := 0
+	if i := strings.Index(url, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	i := strings.Index(url[start:], "//")
+	if i < 0 {
+		return url, ""
+	}
+	return url[:start+i], strings.Trim(url[start+i+2:], "/")
+}
+
+// SplitRev splits a component into its URL and revision. Since revisions
+// cannot contain ":", the user info of URLs like git@code.local:org/zzz.git
+// is not mistaken for a revision.
+func SplitRev(c string) (string, string) {
+	i := strings.LastIndex(c, "@")
+	if i < 0 || strings.Contains(c[i+1:], ":") {
+		return c, ""
+	}
+	url := c[:i]
+	if _, p, ok := strings.Cut(url, "://"); (ok && !strings.Contains(p, "/")) || (!ok && !strings.ContainsAny(url, "/:.")) {
+		// "@" separates the user info and the host
+		return c, ""
+	}
+	return url, c[i+1:]
+}
+
 func ToMap(a any) (m map[string]any) {
 	bs := internal.Must(json.Marshal(a))
 	internal.MustNoErr(json.Unmarshal(bs, &m))
@@ -0,0 +1,92 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Package secret keeps track of credentials used by plugins, so that they can
+// be redacted from logs, check results, evidence and reports.
+package secret
+
+import (
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every occurrence of a registered secret.
+const Mask = "********"
+
+var (
+	mu      sync.RWMutex
+	secrets []string
+)
+
+// Register adds the given values to the secret registry. Empty values are
+// ignored.
+func Register(vals ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, v := range vals {
+		if v == "" || contains(v) {
+			continue
+		}
+		secrets = append(secrets, v)
+	}
+	// replace longer secrets first, in case one secret contains another one
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+}
+
+// Getenv retrieves the value of the environment variable and registers it as
+// secret.
+func Getenv(key string) string {
+	v := os.Getenv(key)
+	Register(v)
+	return v
+}
+
+// Redact replaces all registered secrets in s with Mask.
+func Redact(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, v := range secrets {
+		s = strings.ReplaceAll(s, v, Mask)
+	}
+	return s
+}
+
+// NewWriter returns a writer, which redacts all registered secrets before
+// passing the data to w.
+func NewWriter(w io.Writer) io.Writer {
+	return writer{w}
+}
+
+type writer struct {
+	w io.Writer
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func contains(v string) bool {
+	for _, s := range secrets {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
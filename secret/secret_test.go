@@ -0,0 +1,75 @@
+//  Copyright 2023 The heimdall-dev authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      https://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package secret
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	Register("ghp_token", "ghp_token_long", "", "ghp_token")
+	t.Setenv("HEIMDALL_TEST_SECRET", "s3cr3t")
+	if v := Getenv("HEIMDALL_TEST_SECRET"); v != "s3cr3t" {
+		t.Fatalf("Getenv() = %q, want %q", v, "s3cr3t")
+	}
+
+	tests := []struct {
+		name, in, want string
+	}{
+		{"none", "nothing to hide", "nothing to hide"},
+		{"token", "Authorization: token ghp_token", "Authorization: token " + Mask},
+		{"longer first", "ghp_token_long", Mask},
+		{"repeated", "ghp_token/ghp_token", Mask + "/" + Mask},
+		{"environment", "password=s3cr3t", "password=" + Mask},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.in); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	n := 0
+	for _, s := range secrets {
+		if s == "ghp_token" {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("ghp_token registered %d times, want 1", n)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	Register("hunter2")
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	in := []byte("login with hunter2\n")
+	n, err := w.Write(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// callers must not see a short write, although the output is longer
+	if n != len(in) {
+		t.Errorf("Write() = %d, want %d", n, len(in))
+	}
+	if want := "login with " + Mask + "\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}